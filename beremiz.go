@@ -0,0 +1,316 @@
+// Package beremiz embeds the Beremiz stack language in Go programs.
+//
+//	interp := beremiz.New(beremiz.WithStdout(&out))
+//	stack, err := interp.Eval(ctx, `2 3 + dup writeln`)
+//
+// Errors in the program are returned as Go errors. Problems found in the
+// program are *Diagnostic values; when several are found before running it
+// they come back together as a Diagnostics list. The exit word produces an
+// *ExitError.
+package beremiz
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Diagnostic is a problem found in a program: its error code, message, the
+// span of source that caused it, and any notes or suggested fixes.
+type Diagnostic = err.Diagnostic
+
+// Frame is one word call in the Trace of a runtime *Diagnostic: the word's
+// name and where it was called.
+type Frame = err.Frame
+
+// Diagnostics is a group of diagnostics reported by one Eval call, in source
+// order. errors.As finds each *Diagnostic in it.
+type Diagnostics = err.List
+
+// Loc is a position in a Beremiz source file. Lines and columns start at 1,
+// and columns count runes.
+type Loc = tokens.Loc
+
+// Errors returned, wrapped in a *Diagnostic, when a program goes past one of its
+// Limits. A deadline or cancellation of the context passed to Eval is
+// reported the same way, wrapping ctx.Err().
+var (
+	ErrStepLimit   = parser.ErrStepLimit
+	ErrStackLimit  = parser.ErrStackLimit
+	ErrCallDepth   = parser.ErrCallDepth
+	ErrStringLimit = parser.ErrStringLimit
+)
+
+// Limits bounds the resources a program may use. Zero values mean
+// unlimited.
+type Limits struct {
+	// MaxSteps is the maximum number of instructions executed per Eval.
+	MaxSteps int
+	// MaxStackDepth is the maximum number of values on the data stack.
+	MaxStackDepth int
+	// MaxCallDepth is the maximum nesting of calls to defined words.
+	MaxCallDepth int
+	// MaxStringLen is the maximum length, in bytes, of a string value.
+	MaxStringLen int
+	// Timeout bounds the wall-clock time of each Eval call.
+	Timeout time.Duration
+}
+
+// Capability names a kind of access to the outside world. Built-in words
+// need fs:read (read-file, file-exists, list-dir), fs:write (write-file,
+// append-file, remove-file), env (getenv, setenv) or process (args, exit).
+// No built-in word needs exec, net or time; they are for host words, which
+// declare what they need in Word.Requires.
+type Capability = parser.Capability
+
+const (
+	FSRead  = parser.FSRead
+	FSWrite = parser.FSWrite
+	Env     = parser.Env
+	Process = parser.Process
+	Exec    = parser.Exec
+	Net     = parser.Net
+	Time    = parser.Time
+)
+
+// AllCapabilities lists every capability, for trusted programs.
+var AllCapabilities = parser.Capabilities
+
+// PermissionError is returned, wrapped in a *Diagnostic, when a program calls a
+// word whose capability wasn't granted.
+type PermissionError = parser.PermissionError
+
+// ExitError is returned by Eval when the program stops through the exit word.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Interpreter runs Beremiz programs. It is not safe for concurrent use.
+type Interpreter struct {
+	stdout io.Writer
+	stderr io.Writer
+	stdin  *bufio.Reader
+	args   []string
+	caps   []Capability
+	words  map[string]parser.Word
+	limits Limits
+	// maxErrors caps how many syntax errors are reported; 0 means no cap.
+	maxErrors int
+	hooks     []Hook
+	stack     []any
+}
+
+// Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithStdout sets where write and writeln print to. Defaults to os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.stdout = w }
+}
+
+// WithStderr sets where dump prints to. Defaults to os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) { i.stderr = w }
+}
+
+// WithStdin sets what the read-* words read from. Defaults to os.Stdin. The
+// reader is shared by every Eval call.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) { i.stdin = bufio.NewReader(r) }
+}
+
+// WithArgs sets the arguments pushed by the args word.
+func WithArgs(args ...string) Option {
+	return func(i *Interpreter) { i.args = args }
+}
+
+// WithCapabilities grants caps to programs run by the Interpreter. Nothing
+// is granted by default, so words that touch files or the environment fail
+// with a *PermissionError until their capability is allowed.
+func WithCapabilities(caps ...Capability) Option {
+	return func(i *Interpreter) { i.caps = append(i.caps, caps...) }
+}
+
+// WithLimits sets the resource limits of every Eval call.
+func WithLimits(limits Limits) Option {
+	return func(i *Interpreter) { i.limits = limits }
+}
+
+// DefaultMaxErrors is how many syntax errors Eval reports before giving up,
+// unless WithMaxErrors says otherwise.
+const DefaultMaxErrors = 20
+
+// WithMaxErrors sets how many syntax errors Eval reports at once. Zero
+// reports them all.
+func WithMaxErrors(n int) Option {
+	return func(i *Interpreter) { i.maxErrors = n }
+}
+
+// New returns an Interpreter configured by opts.
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		args:   []string{},
+		words:  map[string]parser.Word{},
+
+		maxErrors: DefaultMaxErrors,
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	if i.stdin == nil {
+		i.stdin = bufio.NewReader(os.Stdin)
+	}
+
+	return i
+}
+
+// Eval runs source as a complete program and returns the final data stack,
+// bottom first. Locations in errors use the file name "<eval>".
+func (i *Interpreter) Eval(ctx context.Context, source string) ([]any, error) {
+	return i.EvalSource(ctx, "<eval>", source)
+}
+
+// EvalSource is like Eval, but uses name as the file name in locations.
+// Each call starts with an empty stack and no definitions.
+func (i *Interpreter) EvalSource(ctx context.Context, name, source string) ([]any, error) {
+	i.stack = nil
+
+	ts, e := i.tokenize(name, source)
+	if e != nil {
+		return nil, e
+	}
+	return i.run(ctx, ts)
+}
+
+// Test is a 'test "name" ... end' block of a program.
+type Test struct {
+	Name string
+	// Loc is where the 'test' keyword is.
+	Loc Loc
+}
+
+// Tests returns the test blocks of source, in order, or the problems that
+// keep the program from running.
+func (i *Interpreter) Tests(name, source string) ([]Test, error) {
+	ts, e := i.tokenize(name, source)
+	if e != nil {
+		return nil, e
+	}
+
+	p := parser.New(ts, false)
+	p.SetMaxErrors(i.maxErrors)
+	p.SetWords(i.words)
+	if e := p.Check(); e != nil {
+		return nil, e
+	}
+
+	var tests []Test
+	for _, t := range parser.Tests(ts) {
+		tests = append(tests, Test{Name: t.Name, Loc: t.Loc})
+	}
+	return tests, nil
+}
+
+// RunTest runs one test block of source, found by Tests, with an empty
+// stack. The words defined at the top level of source are available to
+// it; the rest of the program doesn't run. A failed assertion is returned
+// as a *Diagnostic.
+func (i *Interpreter) RunTest(ctx context.Context, name, source string, test Test) error {
+	i.stack = nil
+
+	ts, e := i.tokenize(name, source)
+	if e != nil {
+		return e
+	}
+
+	for _, t := range parser.Tests(ts) {
+		if t.Loc == test.Loc {
+			_, e := i.run(ctx, parser.TestProgram(ts, t))
+			return e
+		}
+	}
+	return fmt.Errorf("no test %q at %s:%d:%d", test.Name, test.Loc.File, test.Loc.Line, test.Loc.Col)
+}
+
+// tokenize lexes source. Lexer errors come back together with the block
+// errors, so one run shows as many problems as possible.
+func (i *Interpreter) tokenize(name, source string) ([]tokens.Token, error) {
+	lex := lexer.New(source, name)
+	lex.SetMaxErrors(i.maxErrors)
+	ts, lexErr := lex.Tokenize()
+	if lexErr == nil {
+		return ts, nil
+	}
+
+	p := parser.New(ts, false)
+	p.SetMaxErrors(i.maxErrors)
+	p.SetWords(i.words)
+	diagnostics := append(err.Diagnostics(lexErr), err.Diagnostics(p.Check())...)
+	diagnostics.Sort()
+	if i.maxErrors > 0 && len(diagnostics) > i.maxErrors {
+		diagnostics = diagnostics[:i.maxErrors]
+	}
+	return nil, diagnostics
+}
+
+// run evaluates the tokens of a program and keeps the stack it leaves.
+func (i *Interpreter) run(ctx context.Context, ts []tokens.Token) ([]any, error) {
+	if i.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.limits.Timeout)
+		defer cancel()
+	}
+
+	if e := ctx.Err(); e != nil {
+		return nil, e
+	}
+
+	p := parser.New(ts, false)
+	p.SetMaxErrors(i.maxErrors)
+	p.SetInput(i.stdin)
+	p.SetOutput(i.stdout)
+	p.SetErrorOutput(i.stderr)
+	p.SetArgs(i.args)
+	p.SetCapabilities(i.caps...)
+	p.SetWords(i.words)
+	p.SetLimits(parser.Limits{
+		MaxSteps:      i.limits.MaxSteps,
+		MaxStackDepth: i.limits.MaxStackDepth,
+		MaxCallDepth:  i.limits.MaxCallDepth,
+		MaxStringLen:  i.limits.MaxStringLen,
+	})
+	if len(i.hooks) > 0 {
+		p.SetHook(hookAdapter{i.hooks})
+	}
+
+	e := p.Eval(ctx)
+	i.stack = values(p.Stack())
+
+	if exited, code := p.Exited(); exited {
+		return i.stack, &ExitError{Code: code}
+	}
+
+	return i.stack, e
+}
+
+// Stack returns the data stack left by the last Eval call, bottom first.
+// Values are int64, float64, string, bool or nil.
+func (i *Interpreter) Stack() []any {
+	return i.stack
+}
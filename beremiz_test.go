@@ -0,0 +1,190 @@
+package beremiz_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+func TestEval(t *testing.T) {
+	cases := []struct {
+		source string
+		want   []any
+	}{
+		{`1 2 +`, []any{int64(3)}},
+		{`1.5 "a" true nil`, []any{1.5, "a", true, nil}},
+		{`"a" "b" .`, []any{"ab"}},
+		{``, []any{}},
+	}
+
+	for _, c := range cases {
+		interp := beremiz.New()
+		got, e := interp.Eval(context.Background(), c.source)
+		if e != nil {
+			t.Fatalf("Eval(%q): %v", c.source, e)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Eval(%q) = %#v, want %#v", c.source, got, c.want)
+		}
+		if !reflect.DeepEqual(interp.Stack(), c.want) {
+			t.Errorf("Stack() after %q = %#v, want %#v", c.source, interp.Stack(), c.want)
+		}
+	}
+}
+
+func TestEvalStartsFresh(t *testing.T) {
+	interp := beremiz.New()
+	if _, e := interp.Eval(context.Background(), `define two 2 end 1`); e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+
+	// Neither the stack nor the definitions are kept between calls.
+	_, e := interp.Eval(context.Background(), `two`)
+	var d *beremiz.Diagnostic
+	if !errors.As(e, &d) || d.Code != "E204" {
+		t.Errorf("got %v, want 'two' to be undefined", e)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := beremiz.New(
+		beremiz.WithStdout(&stdout),
+		beremiz.WithStderr(&stderr),
+		beremiz.WithStdin(strings.NewReader("line one\nline two\n")),
+		beremiz.WithArgs("a", "b"),
+		beremiz.WithCapabilities(beremiz.Process),
+	)
+
+	source := `args writeln writeln writeln read-line writeln 1 dump pop read-line`
+	stack, e := interp.Eval(context.Background(), source)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+
+	if want := "2\nb\na\nline one\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if stderr.Len() == 0 {
+		t.Error("dump printed nothing to stderr")
+	}
+	if want := []any{"line two"}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %#v, want %#v", stack, want)
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	stack, e := beremiz.New().EvalSource(context.Background(), "script.brz", "1 2\n\"a\" +")
+
+	var d *beremiz.Diagnostic
+	if !errors.As(e, &d) {
+		t.Fatalf("got %v, want a *Diagnostic", e)
+	}
+	if d.Code != "E302" || d.Span.File != "script.brz" || d.Span.StartLine != 2 || d.Span.StartCol != 5 {
+		t.Errorf("got %s at %s:%d:%d, want E302 at script.brz:2:5", d.Code, d.Span.File, d.Span.StartLine, d.Span.StartCol)
+	}
+
+	// The stack is kept as the failing word left it.
+	if want := []any{int64(1)}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %#v, want %#v", stack, want)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	_, e := beremiz.New().Eval(context.Background(), "if 1 do\n\"a\nend end")
+
+	var list beremiz.Diagnostics
+	if !errors.As(e, &list) {
+		t.Fatalf("got %T, want Diagnostics", e)
+	}
+	var codes []string
+	for _, d := range list {
+		codes = append(codes, string(d.Code))
+	}
+	if want := []string{"E103", "E201"}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("codes = %v, want %v", codes, want)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	source := `1 $ 2
+define
+if 1 do 2
+else 3 else 4 end
+3 @ writeln
+end
+1 end
+"open`
+
+	_, e := beremiz.New().EvalSource(context.Background(), "t.brz", source)
+
+	var list beremiz.Diagnostics
+	if !errors.As(e, &list) {
+		t.Fatalf("got %v, want Diagnostics", e)
+	}
+	var got []string
+	for _, d := range list {
+		got = append(got, fmt.Sprintf("%d:%d %s", d.Span.StartLine, d.Span.StartCol, d.Code))
+	}
+	// Every problem is reported, lexer and block errors together, in the
+	// order they are in the source.
+	want := []string{"1:3 E101", "2:1 E202", "4:8 E201", "5:3 E101", "7:3 E201", "8:1 E103"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	source := strings.Repeat("$ ", 30)
+
+	cases := []struct {
+		name string
+		opts []beremiz.Option
+		want int
+	}{
+		{"default", nil, beremiz.DefaultMaxErrors},
+		{"fewer", []beremiz.Option{beremiz.WithMaxErrors(3)}, 3},
+		{"all", []beremiz.Option{beremiz.WithMaxErrors(0)}, 30},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, e := beremiz.New(c.opts...).Eval(context.Background(), source)
+			var list beremiz.Diagnostics
+			if !errors.As(e, &list) || len(list) != c.want {
+				t.Errorf("got %d diagnostics (%v), want %d", len(list), e, c.want)
+			}
+		})
+	}
+}
+
+func TestExitError(t *testing.T) {
+	var stdout bytes.Buffer
+	stack, e := beremiz.New(beremiz.WithStdout(&stdout), beremiz.WithCapabilities(beremiz.Process)).Eval(context.Background(), `"bye" writeln 7 2 exit "never" writeln`)
+
+	var exit *beremiz.ExitError
+	if !errors.As(e, &exit) || exit.Code != 2 {
+		t.Fatalf("got %v, want exit status 2", e)
+	}
+	if stdout.String() != "bye\n" {
+		t.Errorf("stdout = %q, want output before exit to be flushed", stdout.String())
+	}
+	if want := []any{int64(7)}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %#v, want %#v", stack, want)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, e := beremiz.New().Eval(ctx, `1`); !errors.Is(e, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", e)
+	}
+}
@@ -0,0 +1,87 @@
+package beremiz_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+func TestCapabilityDenied(t *testing.T) {
+	dir := t.TempDir()
+	path := strconv.Quote(filepath.Join(dir, "file.txt"))
+
+	cases := []struct {
+		source string
+		word   string
+		cap    beremiz.Capability
+		grant  []beremiz.Capability
+	}{
+		{path + " read-file", "read-file", beremiz.FSRead, nil},
+		{path + " file-exists", "file-exists", beremiz.FSRead, []beremiz.Capability{beremiz.FSWrite}},
+		{strconv.Quote(dir) + " list-dir", "list-dir", beremiz.FSRead, nil},
+		{`"x" ` + path + " write-file", "write-file", beremiz.FSWrite, []beremiz.Capability{beremiz.FSRead}},
+		{`"x" ` + path + " append-file", "append-file", beremiz.FSWrite, nil},
+		{path + " remove-file", "remove-file", beremiz.FSWrite, nil},
+		{`"HOME" getenv`, "getenv", beremiz.Env, nil},
+		{`"v" "BEREMIZ_TEST_VAR" setenv`, "setenv", beremiz.Env, nil},
+		{`args`, "args", beremiz.Process, []beremiz.Capability{beremiz.Env}},
+		{`0 exit`, "exit", beremiz.Process, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.word, func(t *testing.T) {
+			_, e := beremiz.New(beremiz.WithCapabilities(c.grant...)).Eval(context.Background(), c.source)
+
+			var denied *beremiz.PermissionError
+			if !errors.As(e, &denied) || denied.Word != c.word || denied.Capability != c.cap {
+				t.Fatalf("got %v, want '%s' denied for lack of %s", e, c.word, c.cap)
+			}
+			var d *beremiz.Diagnostic
+			if !errors.As(e, &d) || d.Code != "E306" {
+				t.Errorf("got %v, want an E306 diagnostic", e)
+			}
+		})
+	}
+
+	if _, e := os.Stat(filepath.Join(dir, "file.txt")); !os.IsNotExist(e) {
+		t.Errorf("a denied word touched the file system: %v", e)
+	}
+}
+
+func TestCapabilityGranted(t *testing.T) {
+	path := strconv.Quote(filepath.Join(t.TempDir(), "file.txt"))
+	interp := beremiz.New(beremiz.WithCapabilities(beremiz.FSRead, beremiz.FSWrite))
+
+	stack, e := interp.Eval(context.Background(), `"hello" `+path+` write-file `+path+` read-file`)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+	if want := []any{"hello"}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %#v, want %#v", stack, want)
+	}
+}
+
+func TestHostWordRequires(t *testing.T) {
+	called := false
+	lib := &beremiz.Library{Name: "net", Words: []beremiz.Word{{
+		Name:     "ping",
+		Requires: []beremiz.Capability{beremiz.Net},
+		Fn:       func(s *beremiz.Stack) error { called = true; return nil },
+	}}}
+
+	_, e := beremiz.New(beremiz.WithLibraries(lib)).Eval(context.Background(), `ping`)
+	var denied *beremiz.PermissionError
+	if !errors.As(e, &denied) || denied.Capability != beremiz.Net || called {
+		t.Fatalf("got %v (called: %v), want 'ping' denied before running", e, called)
+	}
+
+	if _, e := beremiz.New(beremiz.WithLibraries(lib), beremiz.WithCapabilities(beremiz.AllCapabilities...)).Eval(context.Background(), `ping`); e != nil || !called {
+		t.Errorf("got %v (called: %v), want 'ping' to run", e, called)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/dap"
+)
+
+// dapCommand serves the Debug Adapter Protocol over stdin and stdout.
+func dapCommand(args []string) {
+	var opts options
+	fs := newFlagSet("dap", "dap [flags]", &opts)
+	addRunFlags(fs, &opts)
+	opts.parse(fs, args)
+
+	if e := dap.New(os.Stdin, os.Stdout, opts.interpreterOptions()...).Serve(); e != nil {
+		opts.fatal(e.Error())
+		os.Exit(1)
+	}
+}
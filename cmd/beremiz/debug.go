@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/debug"
+	"github.com/adaiasmagdiel/beremiz-go/internal/pathutils"
+)
+
+// debugCommand runs a file under the interactive debugger.
+func debugCommand(args []string) {
+	var opts options
+	var breakpoints []string
+	var run bool
+
+	fs := newFlagSet("debug", "debug [flags] file.brz [args...]", &opts)
+	addRunFlags(fs, &opts)
+	fs.Func("break", "pause at `file:line`, line or word (repeatable)", func(spec string) error {
+		breakpoints = append(breakpoints, spec)
+		return nil
+	})
+	fs.BoolVar(&run, "run", false, "run until the first breakpoint instead of pausing at the start")
+	args = opts.parse(fs, args)
+
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	name := args[0]
+	path, e := pathutils.ResolveFilePath(name)
+	if e != nil {
+		opts.fatal("Error resolving file path.")
+		os.Exit(1)
+	}
+	bytes, e := os.ReadFile(path)
+	if e != nil {
+		opts.fatal("Unable to get the file content.")
+		os.Exit(1)
+	}
+	source := string(bytes)
+
+	// The program and the debugger share stdin, so neither reads ahead of
+	// the other.
+	stdin := bufio.NewReader(os.Stdin)
+
+	debugger := debug.New(stdin, os.Stdout)
+	debugger.AddSource(name, source)
+	for _, spec := range breakpoints {
+		if _, e := debugger.Break(spec); e != nil {
+			opts.fatal(fmt.Sprintf("Unable to add breakpoint '%s': %v.", spec, e))
+			os.Exit(2)
+		}
+	}
+	if run {
+		debugger.Continue()
+	}
+
+	interp := beremiz.New(append(opts.interpreterOptions(),
+		beremiz.WithArgs(args[1:]...),
+		beremiz.WithStdin(stdin),
+		beremiz.WithHook(debugger),
+	)...)
+
+	_, e = interp.EvalSource(context.Background(), name, source)
+
+	var exit *beremiz.ExitError
+	switch {
+	case errors.Is(e, debug.ErrQuit):
+		os.Exit(1)
+	case errors.As(e, &exit):
+		os.Exit(exit.Code)
+	case e != nil:
+		opts.report(e, source)
+		os.Exit(1)
+	}
+
+	fmt.Println("Program finished.")
+}
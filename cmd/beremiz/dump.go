@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/dump"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+)
+
+// tokensCommand prints the tokens of a file, with the jumps of its blocks
+// resolved.
+func tokensCommand(args []string) {
+	dumpCommand("tokens", args)
+}
+
+// astCommand prints the tree of blocks of a file.
+func astCommand(args []string) {
+	dumpCommand("ast", args)
+}
+
+// dumpCommand runs the tokens or ast command. Whatever could be read of a
+// file with errors is still printed, followed by the errors.
+func dumpCommand(name string, args []string) {
+	var opts options
+	var formatName string
+
+	fs := newFlagSet(name, name+" [flags] file.brz", &opts)
+	fs.StringVar(&formatName, "format", string(dump.FormatHuman), "how the output is written: human or json")
+	args = opts.parse(fs, args)
+
+	if len(args) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	format, e := dump.ParseFormat(formatName)
+	if e != nil {
+		opts.fatal(fmt.Sprintf("%v.", e))
+		os.Exit(2)
+	}
+
+	file := args[0]
+	content, e := os.ReadFile(file)
+	if e != nil {
+		opts.fatal(fmt.Sprintf("Unable to get the content of %s.", file))
+		os.Exit(1)
+	}
+	source := string(content)
+
+	lex := lexer.New(source, file)
+	lex.SetMaxErrors(opts.maxErrors)
+	ts, lexErr := lex.Tokenize()
+
+	// Checking resolves the jumps and builds the tree.
+	p := parser.New(ts, false)
+	p.SetMaxErrors(opts.maxErrors)
+	checkErr := p.Check()
+
+	if name == "tokens" {
+		e = dump.Tokens(os.Stdout, ts, format)
+	} else {
+		e = dump.Tree(os.Stdout, p.Tree(), ts, format)
+	}
+	if e != nil {
+		opts.fatal(fmt.Sprintf("Unable to write the output: %v.", e))
+		os.Exit(1)
+	}
+
+	if lexErr != nil {
+		checkErr = lexErr
+	}
+	if checkErr != nil {
+		opts.report(checkErr, source)
+		os.Exit(1)
+	}
+}
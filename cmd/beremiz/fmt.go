@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/format"
+)
+
+// fmtCommand formats the .brz files under the given paths, or stdin when
+// there are none. By default the result is printed; -w writes it back and
+// --check only lists the files that need formatting.
+func fmtCommand(args []string) {
+	var opts options
+	var write, check bool
+
+	fs := newFlagSet("fmt", "fmt [flags] [path...]", &opts)
+	fs.BoolVar(&write, "w", false, "write the result back to the files instead of printing it")
+	fs.BoolVar(&check, "check", false, "list the files that aren't formatted and exit with 1 if there are any")
+	paths := opts.parse(fs, args)
+
+	if len(paths) == 0 {
+		source, e := io.ReadAll(os.Stdin)
+		if e != nil {
+			opts.fatal(fmt.Sprintf("Unable to read stdin: %v.", e))
+			os.Exit(1)
+		}
+		formatted, e := format.Source("<stdin>", string(source))
+		if e != nil {
+			opts.report(e, string(source))
+			os.Exit(1)
+		}
+		if check {
+			if formatted != string(source) {
+				fmt.Println("<stdin>")
+				os.Exit(1)
+			}
+			return
+		}
+		fmt.Print(formatted)
+		return
+	}
+
+	files, e := findFiles(paths, ".brz")
+	if e != nil {
+		opts.fatal(fmt.Sprintf("Unable to find the files: %v.", e))
+		os.Exit(1)
+	}
+
+	code := 0
+	for _, file := range files {
+		source, e := os.ReadFile(file)
+		if e != nil {
+			opts.fatal(fmt.Sprintf("Unable to get the content of %s.", file))
+			code = 1
+			continue
+		}
+
+		formatted, e := format.Source(file, string(source))
+		if e != nil {
+			opts.report(e, string(source))
+			code = 1
+			continue
+		}
+		changed := !bytes.Equal(source, []byte(formatted))
+
+		switch {
+		case check:
+			if changed {
+				fmt.Println(file)
+				code = 1
+			}
+		case write:
+			if changed {
+				if e := os.WriteFile(file, []byte(formatted), 0o644); e != nil {
+					opts.fatal(fmt.Sprintf("Unable to write %s: %v.", file, e))
+					code = 1
+				}
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFmtCheck(t *testing.T) {
+	dir := t.TempDir()
+	formatted := filepath.Join(dir, "formatted.brz")
+	unformatted := filepath.Join(dir, "unformatted.brz")
+	broken := filepath.Join(dir, "broken.brz")
+	for file, source := range map[string]string{
+		formatted:   "define f\n    1\nend\n",
+		unformatted: "define f\n1\nend\n",
+		broken:      "define f\n",
+	} {
+		if e := os.WriteFile(file, []byte(source), 0o644); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		file   string
+		status int
+		// out is what is printed, unless the file can't be formatted and
+		// its errors are.
+		out string
+	}{
+		{"formatted", formatted, 0, ""},
+		{"unformatted", unformatted, 1, unformatted + "\n"},
+		{"broken", broken, 1, "-"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, status := command(t, "fmt", "--check", c.file)
+			if status != c.status {
+				t.Errorf("exit status = %d, want %d\n%s", status, c.status, out)
+			}
+			if c.out != "-" && out != c.out {
+				t.Errorf("output = %q, want %q", out, c.out)
+			}
+		})
+	}
+
+	// --check leaves the file alone.
+	if source, _ := os.ReadFile(unformatted); string(source) != "define f\n1\nend\n" {
+		t.Errorf("--check changed the file to %q", source)
+	}
+}
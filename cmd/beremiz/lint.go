@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/lint"
+)
+
+// lintConfig is the config file read from the current directory when
+// --config isn't given.
+const lintConfig = "beremiz-lint.json"
+
+// lintCommand reports the lint warnings of the .brz files under the given
+// paths, and exits with 1 if there are any.
+func lintCommand(args []string) {
+	var opts options
+	var configPath, disable string
+	var list bool
+
+	fs := newFlagSet("lint", "lint [flags] [path...]", &opts)
+	fs.StringVar(&configPath, "config", "", "read the rules to turn off from `file` (default "+lintConfig+" if it exists)")
+	fs.StringVar(&disable, "disable", "", "turn off the comma-separated `rules`, by ID or code")
+	fs.BoolVar(&list, "rules", false, "list the rules and exit")
+	paths := opts.parse(fs, args)
+
+	if list {
+		for _, r := range lint.Rules {
+			fmt.Printf("%s  %-18s  %s\n", r.Code, r.ID, r.Doc)
+		}
+		return
+	}
+
+	var config lint.Config
+	var e error
+	switch {
+	case configPath != "":
+		config, e = lint.LoadConfig(configPath)
+	default:
+		config, e = lint.LoadConfig(lintConfig)
+		if errors.Is(e, os.ErrNotExist) {
+			e = nil
+		}
+	}
+	if e != nil {
+		opts.fatal(fmt.Sprintf("Unable to read the lint config: %v.", e))
+		os.Exit(2)
+	}
+
+	if disable != "" {
+		config.Disable = append(config.Disable, strings.Split(disable, ",")...)
+		if e := config.Validate(); e != nil {
+			opts.fatal(fmt.Sprintf("%v.", e))
+			os.Exit(2)
+		}
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, e := findFiles(paths, ".brz")
+	if e != nil {
+		opts.fatal(fmt.Sprintf("Unable to find the files: %v.", e))
+		os.Exit(1)
+	}
+
+	code := 0
+	for _, file := range files {
+		source, e := os.ReadFile(file)
+		if e != nil {
+			opts.fatal(fmt.Sprintf("Unable to get the content of %s.", file))
+			code = 1
+			continue
+		}
+
+		warnings, e := lint.Lint(file, string(source), config)
+		if e != nil {
+			opts.report(e, string(source))
+			code = 1
+			continue
+		}
+		if len(warnings) > 0 {
+			opts.errors.RenderAll(warnings, string(source), false)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
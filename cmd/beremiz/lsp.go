@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/lsp"
+)
+
+// lspCommand serves the Language Server Protocol over stdin and stdout.
+func lspCommand(args []string) {
+	var opts options
+	fs := newFlagSet("lsp", "lsp", &opts)
+	opts.parse(fs, args)
+
+	if e := lsp.New(os.Stdin, os.Stdout).Serve(); e != nil {
+		opts.fatal(e.Error())
+		os.Exit(1)
+	}
+}
@@ -2,24 +2,154 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
-	"path"
 	"strings"
+	"sync"
 	"syscall"
 
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
 	"github.com/adaiasmagdiel/beremiz-go/internal/err"
-	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
-	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
 	"github.com/adaiasmagdiel/beremiz-go/internal/pathutils"
 )
 
+// options holds the command-line flags: the ones every command shares, and
+// the ones of the commands that run programs, added by addRunFlags.
+type options struct {
+	allow    map[beremiz.Capability]*bool
+	allowFS  bool
+	allowAll bool
+	limits   beremiz.Limits
+	errors   *err.Renderer
+	// maxErrors caps how many syntax errors are reported per run.
+	maxErrors   int
+	errorFormat string
+}
+
+// capabilityFlag returns the flag that grants c, e.g. allow-fs-read.
+func capabilityFlag(c beremiz.Capability) string {
+	return "allow-" + strings.ReplaceAll(string(c), ":", "-")
+}
+
+// capabilities returns the capabilities granted by the --allow-* flags.
+func (opts options) capabilities() []beremiz.Capability {
+	var caps []beremiz.Capability
+	for _, c := range beremiz.AllCapabilities {
+		granted := opts.allowAll || *opts.allow[c] ||
+			opts.allowFS && (c == beremiz.FSRead || c == beremiz.FSWrite)
+		if granted {
+			caps = append(caps, c)
+		}
+	}
+	return caps
+}
+
+// interpreterOptions returns the interpreter settings chosen by the flags.
+func (opts options) interpreterOptions() []beremiz.Option {
+	return []beremiz.Option{
+		beremiz.WithCapabilities(opts.capabilities()...),
+		beremiz.WithLimits(opts.limits),
+		beremiz.WithMaxErrors(opts.maxErrors),
+	}
+}
+
+// newFlagSet returns the flags of a command, starting with the ones every
+// command shares, which are stored in opts. usage is the command line shown
+// in help, after "beremiz".
+func newFlagSet(name, usage string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beremiz %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+
+	fs.IntVar(&opts.maxErrors, "max-errors", beremiz.DefaultMaxErrors, "report at most `n` syntax errors per run (0 reports all)")
+	fs.StringVar(&opts.errorFormat, "error-format", string(err.FormatHuman), "how errors are printed: human, json or gcc")
+
+	return fs
+}
+
+// addRunFlags adds the flags of the commands that run programs, granting
+// capabilities and setting limits, to fs. They are stored in opts.
+func addRunFlags(fs *flag.FlagSet, opts *options) {
+	opts.allow = map[beremiz.Capability]*bool{}
+	for _, c := range beremiz.AllCapabilities {
+		opts.allow[c] = fs.Bool(capabilityFlag(c), false, fmt.Sprintf("grant the '%s' capability", c))
+	}
+	fs.BoolVar(&opts.allowFS, "allow-fs", false, "grant both 'fs:read' and 'fs:write'")
+	fs.BoolVar(&opts.allowAll, "allow-all", false, "grant every capability")
+	fs.IntVar(&opts.limits.MaxSteps, "max-steps", 0, "stop after `n` executed instructions (0 is unlimited)")
+	fs.IntVar(&opts.limits.MaxStackDepth, "max-stack", 0, "maximum number of values on the stack (0 is unlimited)")
+	fs.IntVar(&opts.limits.MaxCallDepth, "max-call-depth", 0, "maximum nesting of word calls (0 is unlimited)")
+	fs.IntVar(&opts.limits.MaxStringLen, "max-string", 0, "maximum string length in bytes (0 is unlimited)")
+	fs.DurationVar(&opts.limits.Timeout, "timeout", 0, "stop evaluating after this long, e.g. 5s (0 is unlimited)")
+}
+
+// parse parses the command line of fs and sets up error reporting. It
+// returns the arguments left after the flags.
+func (opts *options) parse(fs *flag.FlagSet, args []string) []string {
+	fs.Parse(args)
+
+	format, e := err.ParseFormat(opts.errorFormat)
+	if e != nil {
+		fmt.Fprintf(os.Stderr, "beremiz: %v\n", e)
+		os.Exit(2)
+	}
+	opts.errors = err.NewTerminalRenderer(os.Stderr, format)
+
+	return fs.Args()
+}
+
+// commands are the subcommands of the CLI, chosen by the first argument.
+// Anything else runs a file, or the REPL when there are no arguments.
+var commands = map[string]func(args []string){
+	"run":    runCommand,
+	"debug":  debugCommand,
+	"dap":    dapCommand,
+	"lsp":    lspCommand,
+	"test":   testCommand,
+	"fmt":    fmtCommand,
+	"lint":   lintCommand,
+	"tokens": tokensCommand,
+	"ast":    astCommand,
+}
+
 func main() {
-	args := os.Args[1:]
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
+	var opts options
+	fs := newFlagSet("beremiz", "[flags] [file.brz [args...]]", &opts)
+	addRunFlags(fs, &opts)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage: beremiz [flags] [file.brz [args...]]
+       beremiz run [flags] file.brz [args...]
+       beremiz debug [flags] file.brz [args...]
+       beremiz dap [flags]
+       beremiz lsp
+       beremiz test [flags] [path...]
+       beremiz fmt [flags] [path...]
+       beremiz lint [flags] [path...]
+       beremiz tokens [flags] file.brz
+       beremiz ast [flags] file.brz
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	args := opts.parse(fs, os.Args[1:])
 
 	if len(args) == 0 {
-		runEval()
+		runEval(opts)
 		return
 	}
 
@@ -27,48 +157,127 @@ func main() {
 
 	filePath, e := pathutils.ResolveFilePath(filename)
 	if e != nil {
-		err.Error("Error resolving file path.\n")
+		opts.fatal("Error resolving file path.")
 		return
 	}
 
-	runFile(filePath)
+	os.Exit(runFile(filename, filePath, args[1:], opts))
 }
 
-func runFile(filepath string) {
+// report prints e to stderr in the chosen error format, quoting the
+// offending line of source when e is a diagnostic.
+func (opts options) report(e error, source string) {
+	diagnostics := err.Diagnostics(e)
+	if len(diagnostics) == 0 {
+		opts.errors.Message(err.SeverityError, e.Error())
+		return
+	}
+
+	var denied *beremiz.PermissionError
+	if errors.As(e, &denied) {
+		diagnostics[0].Suggest("run with --%s to allow it", capabilityFlag(denied.Capability))
+	}
+
+	stopped := opts.maxErrors > 0 && len(diagnostics) >= opts.maxErrors
+	opts.errors.RenderAll(diagnostics, source, stopped)
+}
+
+// fatal prints message to stderr as an error.
+func (opts options) fatal(message string) {
+	opts.errors.Message(err.SeverityError, message)
+}
+
+// runFile runs the file at filepath, with extra interpreter options on top
+// of the ones from the flags, and returns the exit status. Errors are
+// reported against name, the path as it was given on the command line.
+func runFile(name, filepath string, args []string, opts options, extra ...beremiz.Option) int {
 	bytes, e := os.ReadFile(filepath)
 	if e != nil {
-		err.Error("Unable to get the file content.")
-		return
+		opts.fatal("Unable to get the file content.")
+		return 1
 	}
 	content := string(bytes)
 
-	errorHandler := func() {
-		os.Exit(1)
+	interp := beremiz.New(append(append(opts.interpreterOptions(), beremiz.WithArgs(args...)), extra...)...)
+
+	_, e = interp.EvalSource(context.Background(), name, content)
+
+	var exit *beremiz.ExitError
+	if errors.As(e, &exit) {
+		return exit.Code
+	}
+
+	if e != nil {
+		opts.report(e, content)
+		return 1
 	}
+	return 0
+}
+
+// evaluation tracks the input being evaluated in the REPL, so Ctrl-C can
+// stop it instead of quitting.
+type evaluation struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func (ev *evaluation) start() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ev.mu.Lock()
+	ev.cancel = cancel
+	ev.mu.Unlock()
+
+	return ctx
+}
+
+func (ev *evaluation) finish() {
+	ev.mu.Lock()
+	ev.cancel()
+	ev.cancel = nil
+	ev.mu.Unlock()
+}
 
-	lexer := lexer.New(content, path.Base(filepath), errorHandler)
-	tokens := lexer.Tokenize()
+// interrupt stops the running evaluation. It returns false when nothing is
+// running.
+func (ev *evaluation) interrupt() bool {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
 
-	parser := parser.New(tokens, errorHandler, lexer.GetLines(), false)
-	parser.Eval()
+	if ev.cancel == nil {
+		return false
+	}
+	ev.cancel()
+	return true
 }
 
-func runEval() {
+func runEval(opts options) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	reader := bufio.NewReader(os.Stdin)
+	running := &evaluation{}
 
 	go func() {
-		<-sigChan
-		os.Exit(0)
+		for sig := range sigChan {
+			if sig == os.Interrupt && running.interrupt() {
+				continue
+			}
+			os.Exit(0)
+		}
 	}()
 
+	interp := beremiz.New(append(opts.interpreterOptions(), beremiz.WithStdin(reader))...)
+
 	for {
 		fmt.Print("\n> ")
 		input, e := reader.ReadString('\n')
-		if e != nil {
-			err.Error("Unable to read stdin.")
+		if errors.Is(e, io.EOF) && input == "" {
+			fmt.Println()
+			break
+		}
+		if e != nil && !errors.Is(e, io.EOF) {
+			opts.fatal("Unable to read stdin.")
 			continue
 		}
 
@@ -79,7 +288,7 @@ func runEval() {
 		}
 
 		if input != "" {
-			processInput(input)
+			processInput(interp, running, input, opts)
 		}
 	}
 }
@@ -94,7 +303,7 @@ func shouldExit(input string) bool {
 	return false
 }
 
-func processInput(input string) {
+func processInput(interp *beremiz.Interpreter, running *evaluation, input string, opts options) {
 	switch input {
 	case ".help":
 		printHelp()
@@ -104,14 +313,18 @@ func processInput(input string) {
 		return
 	}
 
-	errorHandler := func() {
-	}
+	ctx := running.start()
+	_, e := interp.EvalSource(ctx, "stdin", input)
+	running.finish()
 
-	lexer := lexer.New(input, "stdin", errorHandler)
-	tokens := lexer.Tokenize()
+	var exit *beremiz.ExitError
+	if errors.As(e, &exit) {
+		os.Exit(exit.Code)
+	}
 
-	parser := parser.New(tokens, errorHandler, lexer.GetLines(), true)
-	parser.Eval()
+	if e != nil {
+		opts.report(e, input)
+	}
 }
 
 func printHelp() {
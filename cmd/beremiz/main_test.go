@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command instead of the tests when asked to, so the
+// tests can run it as a process and see its exit status.
+func TestMain(m *testing.M) {
+	if os.Getenv("BEREMIZ_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// command runs beremiz with args and returns what it printed and its
+// exit status.
+func command(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "BEREMIZ_TEST_MAIN=1")
+	out, e := cmd.CombinedOutput()
+
+	var exit *exec.ExitError
+	if errors.As(e, &exit) {
+		return string(out), exit.ExitCode()
+	}
+	if e != nil {
+		t.Fatalf("running beremiz %s: %v", strings.Join(args, " "), e)
+	}
+	return string(out), 0
+}
+
+func TestRunFlags(t *testing.T) {
+	cases := []struct {
+		command string
+		runs    bool
+	}{
+		{"run", true},
+		{"test", true},
+		{"debug", true},
+		{"dap", true},
+		{"fmt", false},
+		{"lint", false},
+		{"tokens", false},
+		{"ast", false},
+		{"lsp", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.command, func(t *testing.T) {
+			out, _ := command(t, c.command, "-h")
+			for _, flag := range []string{"-allow-all", "-allow-fs-read", "-max-steps", "-timeout"} {
+				if strings.Contains(out, flag) != c.runs {
+					t.Errorf("'%s -h' lists %s: %v, want %v", c.command, flag, !c.runs, c.runs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/coverage"
+	"github.com/adaiasmagdiel/beremiz-go/internal/pathutils"
+	"github.com/adaiasmagdiel/beremiz-go/internal/profile"
+	"github.com/adaiasmagdiel/beremiz-go/internal/trace"
+)
+
+// runCommand runs a file, like running beremiz with a file name, with extra
+// flags to watch it run.
+func runCommand(args []string) {
+	var opts options
+	var traceOn bool
+	var traceOut, traceWord, traceLines, traceFormat string
+	var profileOn bool
+	var profileOut string
+	var coverageOut, coverageHTML string
+
+	fs := newFlagSet("run", "run [flags] file.brz [args...]", &opts)
+	addRunFlags(fs, &opts)
+	fs.BoolVar(&traceOn, "trace", false, "print each instruction and the stack after it to stderr")
+	fs.StringVar(&traceOut, "trace-out", "", "write the trace to `file` instead of stderr")
+	fs.StringVar(&traceWord, "trace-word", "", "only trace what runs inside calls of `word`")
+	fs.StringVar(&traceLines, "trace-lines", "", "only trace instructions on lines `N`, N-M or N-")
+	fs.StringVar(&traceFormat, "trace-format", string(trace.FormatText), "how the trace is written: text or json")
+	fs.BoolVar(&profileOn, "profile", false, "print instruction counts and times per word and per line to stderr on exit")
+	fs.StringVar(&profileOut, "profile-out", "", "write a pprof profile to `file` on exit")
+	fs.StringVar(&coverageOut, "coverage", "", "write what ran to `file` as JSON and print a coverage summary to stderr on exit")
+	fs.StringVar(&coverageHTML, "coverage-html", "", "write a coverage report annotating the source to `file` on exit")
+	args = opts.parse(fs, args)
+
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var extra []beremiz.Option
+
+	// traceFile is closed before exiting: os.Exit skips deferred calls.
+	var traceFile *os.File
+	if traceOn || traceOut != "" {
+		format, e := trace.ParseFormat(traceFormat)
+		if e != nil {
+			opts.fatal(fmt.Sprintf("%v.", e))
+			os.Exit(2)
+		}
+
+		w := os.Stderr
+		if traceOut != "" {
+			if traceFile, e = os.Create(traceOut); e != nil {
+				opts.fatal(fmt.Sprintf("Unable to create the trace file: %v.", e))
+				os.Exit(1)
+			}
+			w = traceFile
+		}
+
+		tracer := trace.New(w, format)
+		tracer.Word = traceWord
+		if traceLines != "" {
+			lines, e := trace.ParseLines(traceLines)
+			if e != nil {
+				opts.fatal(fmt.Sprintf("%v.", e))
+				os.Exit(2)
+			}
+			tracer.Lines = &lines
+		}
+		extra = append(extra, beremiz.WithHook(tracer))
+	}
+
+	var profiler *profile.Profiler
+	if profileOn || profileOut != "" {
+		profiler = profile.New()
+		extra = append(extra, beremiz.WithHook(profiler))
+	}
+
+	name := args[0]
+	path, e := pathutils.ResolveFilePath(name)
+	if e != nil {
+		opts.fatal("Error resolving file path.")
+		os.Exit(1)
+	}
+
+	var cover *coverage.Coverage
+	if coverageOut != "" || coverageHTML != "" {
+		source, e := os.ReadFile(path)
+		if e != nil {
+			opts.fatal("Unable to get the file content.")
+			os.Exit(1)
+		}
+		cover = coverage.New(name, string(source))
+		extra = append(extra, beremiz.WithHook(cover))
+	}
+
+	code := runFile(name, path, args[1:], opts, extra...)
+	if traceFile != nil {
+		if e := traceFile.Close(); e != nil {
+			opts.fatal(fmt.Sprintf("Unable to write the trace: %v.", e))
+			code = max(code, 1)
+		}
+	}
+	if cover != nil {
+		cover.Done()
+	}
+
+	if profileOn {
+		fmt.Fprintln(os.Stderr)
+		profiler.Report(os.Stderr)
+	}
+	if profileOut != "" {
+		if e := writeFile(profileOut, profiler.WritePprof); e != nil {
+			opts.fatal(fmt.Sprintf("Unable to write the profile: %v.", e))
+			code = max(code, 1)
+		}
+	}
+
+	if coverageOut != "" {
+		fmt.Fprintln(os.Stderr)
+		cover.Summary(os.Stderr)
+		if e := writeFile(coverageOut, cover.WriteJSON); e != nil {
+			opts.fatal(fmt.Sprintf("Unable to write the coverage: %v.", e))
+			code = max(code, 1)
+		}
+	}
+	if coverageHTML != "" {
+		if e := writeFile(coverageHTML, cover.WriteHTML); e != nil {
+			opts.fatal(fmt.Sprintf("Unable to write the coverage report: %v.", e))
+			code = max(code, 1)
+		}
+	}
+
+	os.Exit(code)
+}
+
+// writeFile creates the file at path and fills it with write.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, e := os.Create(path)
+	if e != nil {
+		return e
+	}
+	if e := write(f); e != nil {
+		f.Close()
+		return e
+	}
+	return f.Close()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTraceOut(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "exit.brz")
+	if e := os.WriteFile(program, []byte("1 2 + 3 exit"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+	out := filepath.Join(dir, "trace.txt")
+
+	// The program exits with its own status; the trace is written anyway.
+	if output, status := command(t, "run", "--allow-process", "--trace-out", out, program); status != 3 {
+		t.Fatalf("exit status = %d, want 3\n%s", status, output)
+	}
+
+	trace, e := os.ReadFile(out)
+	if e != nil {
+		t.Fatal(e)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(trace), "\n"), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(lines[3], "[3 3]") {
+		t.Errorf("trace =\n%s\nwant the 4 instructions before exit", trace)
+	}
+}
+
+func TestErrorFormat(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "bad.brz")
+	if e := os.WriteFile(program, []byte("1 $ 2 @"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{"gcc", program + ":1:3: error: "},
+		{"json", `{"severity":"error","code":"E101","kind":"LexerError",`},
+		{"human", "LexerError[E101]: "},
+	}
+
+	for _, c := range cases {
+		t.Run(c.format, func(t *testing.T) {
+			out, status := command(t, "run", "--error-format", c.format, program)
+			if status != 1 {
+				t.Errorf("exit status = %d, want 1", status)
+			}
+			if !strings.HasPrefix(out, c.want) {
+				t.Errorf("output = %q, want it to start with %q", out, c.want)
+			}
+			if strings.Contains(out, "\x1b[") {
+				t.Errorf("output = %q, want no colors when stderr isn't a terminal", out)
+			}
+		})
+	}
+
+	if out, status := command(t, "run", "--error-format", "xml", program); status != 2 {
+		t.Errorf("exit status = %d, want 2 for an unknown format\n%s", status, out)
+	}
+}
+
+func TestMaxErrorsFlag(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "bad.brz")
+	if e := os.WriteFile(program, []byte(strings.Repeat("$ ", 30)), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	cases := []struct {
+		flag   string
+		count  int
+		ending string
+	}{
+		{"", 20, "\nStopped after 20 errors.\n"},
+		{"2", 2, "\nStopped after 2 errors.\n"},
+		{"0", 30, "\nFound 30 errors.\n"},
+	}
+
+	for _, c := range cases {
+		t.Run("max-errors="+c.flag, func(t *testing.T) {
+			args := []string{"run", program}
+			if c.flag != "" {
+				args = []string{"run", "--max-errors", c.flag, program}
+			}
+			out, status := command(t, args...)
+			if status != 1 {
+				t.Errorf("exit status = %d, want 1", status)
+			}
+			if n := strings.Count(out, "LexerError[E101]"); n != c.count {
+				t.Errorf("printed %d errors, want %d", n, c.count)
+			}
+			if !strings.HasSuffix(out, c.ending) {
+				t.Errorf("output ends with %q, want %q", out[max(len(out)-40, 0):], c.ending)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+// testSuffix ends the name of every file holding tests.
+const testSuffix = "_test.brz"
+
+// testCommand runs the test blocks of every *_test.brz file under the given
+// paths, each in a fresh interpreter, and exits with 1 if any failed.
+func testCommand(args []string) {
+	var opts options
+	var verbose bool
+	var match string
+
+	fs := newFlagSet("test", "test [flags] [path...]", &opts)
+	addRunFlags(fs, &opts)
+	fs.BoolVar(&verbose, "v", false, "list every test run, not only the ones that failed")
+	fs.StringVar(&match, "run", "", "only run tests whose name contains `text`")
+	paths := opts.parse(fs, args)
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, e := findFiles(paths, testSuffix)
+	if e != nil {
+		opts.fatal(fmt.Sprintf("Unable to find the test files: %v.", e))
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Println("No test files found.")
+		return
+	}
+
+	var passed, failed int
+	for _, file := range files {
+		p, f := runTests(file, match, verbose, opts)
+		passed += p
+		failed += f
+	}
+
+	fmt.Printf("\n%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// findFiles returns the files named by paths, and the files whose name ends
+// with suffix in the directories among them, looking through them
+// recursively.
+func findFiles(paths []string, suffix string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, e := os.Stat(path)
+		if e != nil {
+			return nil, e
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		e = filepath.WalkDir(path, func(name string, d fs.DirEntry, e error) error {
+			if e != nil {
+				return e
+			}
+			if !d.IsDir() && strings.HasSuffix(name, suffix) {
+				files = append(files, name)
+			}
+			return nil
+		})
+		if e != nil {
+			return nil, e
+		}
+	}
+	return files, nil
+}
+
+// runTests runs the tests of one file and returns how many passed and
+// failed. A file that can't run counts as one failure.
+func runTests(file, match string, verbose bool, opts options) (passed, failed int) {
+	content, e := os.ReadFile(file)
+	if e != nil {
+		opts.fatal(fmt.Sprintf("Unable to get the content of %s.", file))
+		return 0, 1
+	}
+	source := string(content)
+
+	tests, e := beremiz.New(opts.interpreterOptions()...).Tests(file, source)
+	if e != nil {
+		fmt.Printf("FAIL %s\n", file)
+		opts.report(e, source)
+		return 0, 1
+	}
+
+	start := time.Now()
+	for _, test := range tests {
+		if !strings.Contains(test.Name, match) {
+			continue
+		}
+
+		// Output is kept, and only shown for the tests that fail.
+		var out bytes.Buffer
+		interp := beremiz.New(append(opts.interpreterOptions(),
+			beremiz.WithStdout(&out), beremiz.WithStderr(&out))...)
+
+		began := time.Now()
+		e := interp.RunTest(context.Background(), file, source, test)
+		took := time.Since(began).Round(time.Microsecond)
+
+		var exit *beremiz.ExitError
+		if errors.As(e, &exit) {
+			e = fmt.Errorf("test %q called exit with status %d", test.Name, exit.Code)
+		}
+
+		if e == nil {
+			passed++
+			if verbose {
+				fmt.Printf("--- PASS: %s (%s)\n", test.Name, took)
+			}
+			continue
+		}
+
+		failed++
+		fmt.Printf("--- FAIL: %s (%s:%d:%d, %s)\n", test.Name, file, test.Loc.Line, test.Loc.Col, took)
+		if out.Len() > 0 {
+			for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+		opts.report(e, source)
+	}
+
+	status := "ok  "
+	if failed > 0 {
+		status = "FAIL"
+	}
+	fmt.Printf("%s %s\t%d passed, %d failed (%s)\n", status, file, passed, failed, time.Since(start).Round(time.Microsecond))
+	return passed, failed
+}
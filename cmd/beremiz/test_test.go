@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTestCommand(t *testing.T) {
+	dir := filepath.Join("..", "..", "testdata")
+	cases := []struct {
+		args    []string
+		status  int
+		summary string
+	}{
+		{[]string{"test", filepath.Join(dir, "pass_test.brz")}, 0, "5 passed, 0 failed"},
+		{[]string{"test", filepath.Join(dir, "fail_test.brz")}, 1, "1 passed, 4 failed"},
+		{[]string{"test", "-run", "passing", filepath.Join(dir, "fail_test.brz")}, 0, "1 passed, 0 failed"},
+		// 'double' is a host word, unknown to the command.
+		{[]string{"test", filepath.Join(dir, "host_test.brz")}, 1, "0 passed, 1 failed"},
+	}
+
+	for _, c := range cases {
+		t.Run(strings.Join(c.args, " "), func(t *testing.T) {
+			out, status := command(t, c.args...)
+			if status != c.status {
+				t.Errorf("exit status = %d, want %d\n%s", status, c.status, out)
+			}
+			if !strings.HasSuffix(out, "\n"+c.summary+"\n") {
+				t.Errorf("output ends with %q, want %q", out[strings.LastIndex(strings.TrimSuffix(out, "\n"), "\n")+1:], c.summary+"\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package beremiz_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+)
+
+var update = flag.Bool("update", false, "rewrite the .out and .err golden files of the examples")
+
+// TestExamples runs every program in examples/ and compares what it prints
+// with the golden files next to it: name.out holds stdout and name.err
+// stderr, including errors and the exit status. A missing .err file means
+// nothing is expected on stderr. Run with -update to rewrite them.
+func TestExamples(t *testing.T) {
+	files, e := filepath.Glob(filepath.Join("examples", "*.brz"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(files) == 0 {
+		t.Fatal("no examples found")
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.brz") {
+			continue
+		}
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			stdout, stderr := runExample(t, file)
+			base := strings.TrimSuffix(file, ".brz")
+
+			if *update {
+				writeGolden(t, base+".out", stdout, true)
+				writeGolden(t, base+".err", stderr, false)
+				return
+			}
+
+			compareGolden(t, base+".out", stdout)
+			compareGolden(t, base+".err", stderr)
+		})
+	}
+}
+
+// runExample runs file with no input and returns what it wrote to stdout
+// and stderr.
+func runExample(t *testing.T, file string) (string, string) {
+	t.Helper()
+
+	source, e := os.ReadFile(file)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var stdout, stderr bytes.Buffer
+	interp := beremiz.New(
+		beremiz.WithStdout(&stdout),
+		beremiz.WithStderr(&stderr),
+		beremiz.WithStdin(strings.NewReader("")),
+		beremiz.WithLimits(beremiz.Limits{Timeout: 10 * time.Second}),
+	)
+
+	_, e = interp.EvalSource(context.Background(), filepath.ToSlash(file), string(source))
+
+	var exit *beremiz.ExitError
+	switch {
+	case errors.As(e, &exit):
+		fmt.Fprintf(&stderr, "exit status %d\n", exit.Code)
+	case e != nil:
+		diagnostics := err.Diagnostics(e)
+		if len(diagnostics) == 0 {
+			t.Fatalf("unexpected error: %v", e)
+		}
+		err.NewRenderer(&stderr, err.FormatHuman, false).RenderAll(diagnostics, string(source), false)
+	}
+
+	return stdout.String(), stderr.String()
+}
+
+// writeGolden writes content to path. Empty content removes the file
+// instead, unless always is set.
+func writeGolden(t *testing.T, path, content string, always bool) {
+	t.Helper()
+
+	if content == "" && !always {
+		if e := os.Remove(path); e != nil && !errors.Is(e, os.ErrNotExist) {
+			t.Fatal(e)
+		}
+		return
+	}
+	if e := os.WriteFile(path, []byte(content), 0o644); e != nil {
+		t.Fatal(e)
+	}
+}
+
+// compareGolden fails the test when got differs from the content of path,
+// showing the first line that differs.
+func compareGolden(t *testing.T, path, got string) {
+	t.Helper()
+
+	want, e := os.ReadFile(path)
+	if e != nil && !errors.Is(e, os.ErrNotExist) {
+		t.Fatal(e)
+	}
+	if got == string(want) {
+		return
+	}
+
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			t.Errorf("%s differs at line %d (run go test -update to accept the new output):\n  want: %q\n   got: %q", path, i+1, w, g)
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package beremiz_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+// fsInterp returns an interpreter allowed to read and write files.
+func fsInterp() *beremiz.Interpreter {
+	return beremiz.New(beremiz.WithCapabilities(beremiz.FSRead, beremiz.FSWrite))
+}
+
+func TestFileWords(t *testing.T) {
+	dir := t.TempDir()
+	q := func(name string) string { return strconv.Quote(filepath.Join(dir, name)) }
+	if e := os.Mkdir(filepath.Join(dir, "sub"), 0o755); e != nil {
+		t.Fatal(e)
+	}
+
+	cases := []struct {
+		name   string
+		source string
+		want   []any
+	}{
+		{"write and read", `"one" ` + q("a.txt") + ` write-file ` + q("a.txt") + ` read-file`, []any{"one"}},
+		{"write replaces", `"two" ` + q("a.txt") + ` write-file ` + q("a.txt") + ` read-file`, []any{"two"}},
+		{"append", `"3" ` + q("a.txt") + ` append-file ` + q("a.txt") + ` read-file`, []any{"two3"}},
+		{"append creates", `"x" ` + q("b.txt") + ` append-file ` + q("b.txt") + ` read-file`, []any{"x"}},
+		{"exists", q("a.txt") + ` file-exists ` + q("sub") + ` file-exists ` + q("none") + ` file-exists`, []any{true, true, false}},
+		{"list-dir", q("") + ` list-dir`, []any{"a.txt", "b.txt", "sub", int64(3)}},
+		{"empty dir", q("sub") + ` list-dir`, []any{int64(0)}},
+		{"remove", q("b.txt") + ` remove-file ` + q("b.txt") + ` file-exists`, []any{false}},
+	}
+
+	// The cases run in order, each on the files the ones before left.
+	for _, c := range cases {
+		stack, e := fsInterp().Eval(context.Background(), c.source)
+		if e != nil {
+			t.Fatalf("%s: Eval: %v", c.name, e)
+		}
+		if !reflect.DeepEqual(stack, c.want) {
+			t.Errorf("%s: stack = %#v, want %#v", c.name, stack, c.want)
+		}
+	}
+}
+
+func TestFileHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if e := os.WriteFile(filepath.Join(home, "notes.txt"), []byte("hi"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	stack, e := fsInterp().Eval(context.Background(), `"~/notes.txt" read-file "!" "~/out.txt" write-file`)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+	if want := []any{"hi"}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %#v, want %#v", stack, want)
+	}
+	if content, e := os.ReadFile(filepath.Join(home, "out.txt")); e != nil || string(content) != "!" {
+		t.Errorf("~/out.txt = %q, %v; want it written in the home directory", content, e)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	missing := strconv.Quote(filepath.Join(dir, "missing", "file.txt"))
+	q := strconv.Quote(dir)
+
+	cases := []struct {
+		name    string
+		source  string
+		message string
+	}{
+		{"read missing", missing + ` read-file`, "Unable to read file"},
+		{"read a directory", q + ` read-file`, "Unable to read file"},
+		{"write in a missing directory", `"x" ` + missing + ` write-file`, "Unable to write file"},
+		{"append in a missing directory", `"x" ` + missing + ` append-file`, "Unable to open file"},
+		{"list missing", missing + ` list-dir`, "Unable to list directory"},
+		{"remove missing", missing + ` remove-file`, "Unable to remove"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, e := fsInterp().Eval(context.Background(), c.source)
+
+			var d *beremiz.Diagnostic
+			if !errors.As(e, &d) || d.Code != "E305" || !strings.HasPrefix(d.Message, c.message) {
+				t.Errorf("got %v, want an E305 diagnostic starting with %q", e, c.message)
+			}
+		})
+	}
+
+	// The errors can be caught like any other.
+	source := `test "caught" assert-throws ` + missing + ` read-file end end`
+	results := map[string]error{}
+	interp := fsInterp()
+	tests, e := interp.Tests("t.brz", source)
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, test := range tests {
+		results[test.Name] = interp.RunTest(context.Background(), "t.brz", source, test)
+	}
+	if want := map[string]error{"caught": nil}; !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %v, want the error caught by assert-throws", results)
+	}
+}
+
+func TestFilePermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("file permissions don't apply to root")
+	}
+
+	dir := t.TempDir()
+	locked := filepath.Join(dir, "locked")
+	if e := os.Mkdir(locked, 0o755); e != nil {
+		t.Fatal(e)
+	}
+	if e := os.WriteFile(filepath.Join(locked, "file.txt"), []byte("x"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+	if e := os.Chmod(locked, 0); e != nil {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	file := strconv.Quote(filepath.Join(locked, "file.txt"))
+	for _, source := range []string{
+		file + ` read-file`,
+		`"y" ` + file + ` write-file`,
+		strconv.Quote(locked) + ` list-dir`,
+		file + ` remove-file`,
+	} {
+		_, e := fsInterp().Eval(context.Background(), source)
+
+		var d *beremiz.Diagnostic
+		if !errors.As(e, &d) || d.Code != "E305" || !strings.Contains(d.Message, "permission denied") {
+			t.Errorf("%s: got %v, want an E305 diagnostic saying permission denied", source, e)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package beremiz
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Hook watches programs run, for debuggers, tracers and profilers. Before is
+// called before each instruction and After once it ran without error. An
+// error from either stops the program, and Eval returns it as it is.
+type Hook interface {
+	Before(ev *Event) error
+	After(ev *Event) error
+}
+
+// EventKind tells what an Event is about.
+type EventKind int
+
+const (
+	// Instruction is a value or word written in the program.
+	Instruction EventKind = iota
+	// Call starts a call of a defined word. The word's body follows.
+	Call
+	// Return ends the call of a defined word.
+	Return
+)
+
+// Event is one step of a running program, as a Hook sees it. It is only
+// valid during the call it was passed to.
+type Event struct {
+	Kind EventKind
+	// Word is the instruction as it would be written in the source: a
+	// literal value (strings are quoted), a keyword, an operator or the name
+	// of a word. For Call and Return it is the name of the defined word.
+	Word string
+	// Loc is where the instruction is written. For Call and Return it is
+	// where the word was called.
+	Loc Loc
+	// Frames lists the active word calls, outermost first.
+	Frames []Frame
+
+	state *parser.State
+}
+
+// WithHook makes every Eval call report its steps to h. It can be given more
+// than once, and the hooks are called in that order. Running with a hook is
+// slower, but without one Eval is unaffected.
+func WithHook(h Hook) Option {
+	return func(i *Interpreter) { i.hooks = append(i.hooks, h) }
+}
+
+// Stack returns a copy of the data stack, bottom first.
+func (ev *Event) Stack() []any {
+	return values(ev.state.Stack)
+}
+
+// Flush writes out anything the program printed so far, so it shows up
+// before a debugger's own output.
+func (ev *Event) Flush() error {
+	return ev.state.Flush()
+}
+
+// Eval runs source against a copy of the data stack, where the words defined
+// by the program can be called, and returns the stack it leaves. The
+// program's own stack is not changed.
+func (ev *Event) Eval(ctx context.Context, source string) ([]any, error) {
+	stack, e := ev.state.Eval(ctx, source)
+	return values(stack), e
+}
+
+// hookAdapter lets Hooks watch the parser.
+type hookAdapter struct {
+	hooks []Hook
+}
+
+func (a hookAdapter) Before(s *parser.State) error {
+	ev := newEvent(s)
+	for _, h := range a.hooks {
+		if e := h.Before(ev); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func (a hookAdapter) After(s *parser.State) error {
+	ev := newEvent(s)
+	for _, h := range a.hooks {
+		if e := h.After(ev); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func newEvent(s *parser.State) *Event {
+	ev := &Event{
+		Word:   word(s.Token),
+		Loc:    s.Token.Loc,
+		Frames: s.Frames,
+		state:  s,
+	}
+
+	switch s.Token.Type {
+	case tokens.Call:
+		ev.Kind = Call
+	case tokens.Return:
+		ev.Kind = Return
+	}
+	return ev
+}
+
+// word returns token as it would be written in the source.
+func word(token tokens.Token) string {
+	switch v := token.Literal.(type) {
+	case string:
+		if token.Type == tokens.String {
+			return strconv.Quote(v)
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
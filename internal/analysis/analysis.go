@@ -0,0 +1,119 @@
+// Package analysis answers questions about a program without running it:
+// where words are defined and used, what the built-in words do and how many
+// values a word takes from and leaves on the stack. Editor tooling builds on
+// it.
+package analysis
+
+import (
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Definition is a word made with 'define'.
+type Definition struct {
+	Name string
+	// Define, Ident and End are the 'define' keyword, the name after it and
+	// the 'end' closing the body. End is the EOF token when the body is
+	// never closed.
+	Define tokens.Token
+	Ident  tokens.Token
+	End    tokens.Token
+	Body   []tokens.Token
+}
+
+// Definitions returns the words defined in ts, in source order.
+func Definitions(ts []tokens.Token) []Definition {
+	var defs []Definition
+
+	for i := 0; i+1 < len(ts); i++ {
+		if ts[i].Type != tokens.Define || ts[i+1].Type != tokens.Identifier {
+			continue
+		}
+
+		def := Definition{
+			Name:   ts[i+1].Literal.(string),
+			Define: ts[i],
+			Ident:  ts[i+1],
+		}
+
+		end := closing(ts, i+2)
+		def.Body = ts[i+2 : end]
+		if end < len(ts) {
+			def.End = ts[end]
+		} else {
+			def.End = tokens.Token{Type: tokens.EOF, Loc: ts[len(ts)-1].Loc}
+		}
+
+		defs = append(defs, def)
+	}
+
+	return defs
+}
+
+// closing returns the index of the 'end' closing the block whose body starts
+// at start, or len(ts) when there is none.
+func closing(ts []tokens.Token, start int) int {
+	depth := 1
+	for j := start; j < len(ts); j++ {
+		switch ts[j].Type {
+		case tokens.Define, tokens.If, tokens.For, tokens.Test, tokens.AssertStack, tokens.AssertThrows:
+			depth++
+		case tokens.End:
+			depth--
+		case tokens.EOF:
+			return j
+		}
+		if depth == 0 {
+			return j
+		}
+	}
+	return len(ts)
+}
+
+// Lookup returns the last definition of name, which is the one a call runs.
+func Lookup(defs []Definition, name string) (Definition, bool) {
+	for i := len(defs) - 1; i >= 0; i-- {
+		if defs[i].Name == name {
+			return defs[i], true
+		}
+	}
+	return Definition{}, false
+}
+
+// References returns every identifier in ts naming name, including the
+// names after 'define'.
+func References(ts []tokens.Token, name string) []tokens.Token {
+	var refs []tokens.Token
+	for _, token := range ts {
+		if token.Type == tokens.Identifier && token.Literal == name {
+			refs = append(refs, token)
+		}
+	}
+	return refs
+}
+
+// At returns the token written at loc, if any. Only the line and column of
+// loc are compared.
+func At(ts []tokens.Token, loc tokens.Loc) (tokens.Token, bool) {
+	for _, token := range ts {
+		if token.Type == tokens.EOF || token.Loc.Line != loc.Line {
+			continue
+		}
+		if loc.Col >= token.Loc.Col && loc.Col < token.Loc.Col+max(token.Len, 1) {
+			return token, true
+		}
+	}
+	return tokens.Token{}, false
+}
+
+// Name returns the name token stands for: the identifier, keyword or
+// operator as written. Literals have no name.
+func Name(token tokens.Token) (string, bool) {
+	switch token.Type {
+	case tokens.Int, tokens.Float, tokens.String, tokens.Bool, tokens.EOF:
+		return "", false
+	case tokens.Nil:
+		return "nil", true
+	}
+	name, ok := token.Literal.(string)
+	return name, ok
+}
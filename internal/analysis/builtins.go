@@ -0,0 +1,80 @@
+package analysis
+
+// Builtin describes a keyword or an operator.
+type Builtin struct {
+	// Effect is the stack effect as the README writes it: the values taken,
+	// top last, then the values left. Block keywords show their syntax
+	// instead.
+	Effect string
+	Doc    string
+	// In and Out count the values taken and left. Out is -1 when it depends
+	// on the values, and both are -1 for block keywords, which effect
+	// inference handles itself.
+	In, Out int
+}
+
+// Builtins holds every keyword and operator, by name.
+var Builtins = map[string]Builtin{
+	"write":   {"a ->", "Print the top of the stack.", 1, 0},
+	"writeln": {"a ->", "Print the top of the stack, then a newline.", 1, 0},
+	"type":    {"a -> a type", "Push the type of the top of the stack, as a string, leaving the value in place.", 1, 2},
+	"define":  {"define name ... end", "Define a word. Calling it runs the body in place.", -1, -1},
+
+	"read-line":   {"-> line", "Push the next line of stdin without its newline, or `nil` at the end of input.", 0, 1},
+	"read-all":    {"-> content", "Push everything left on stdin as a single string.", 0, 1},
+	"read-number": {"-> number", "Read a line of stdin and parse it as a number literal.", 0, 1},
+
+	"read-file":   {"path -> content", "Read a whole file. Needs `fs:read`.", 1, 1},
+	"write-file":  {"content path ->", "Create or truncate a file. Needs `fs:write`.", 2, 0},
+	"append-file": {"content path ->", "Append to a file, creating it if needed. Needs `fs:write`.", 2, 0},
+	"file-exists": {"path -> bool", "Check whether a path exists. Needs `fs:read`.", 1, 1},
+	"list-dir":    {"path -> name... count", "Push every entry of a directory (sorted), then the count. Needs `fs:read`.", 1, -1},
+	"remove-file": {"path ->", "Remove a file or an empty directory. Needs `fs:write`.", 1, 0},
+
+	"args":   {"-> arg... count", "Push the script arguments, then their count. Needs `process`.", 0, -1},
+	"getenv": {"name -> value", "Read an environment variable (`nil` if unset). Needs `env`.", 1, 1},
+	"setenv": {"value name ->", "Set an environment variable. Needs `env`.", 2, 0},
+	"exit":   {"code ->", "Stop the program with the given status code (0-255). Needs `process`.", 1, 0},
+
+	"nil": {"-> nil", "Push the empty value.", 0, 1},
+
+	"for":  {"for cond do body end", "Run body while cond leaves a true value.", -1, -1},
+	"if":   {"if cond do body [elif cond do body] [else body] end", "Run the first branch whose cond leaves a true value.", -1, -1},
+	"elif": {"elif cond do body", "Another branch of an `if`.", -1, -1},
+	"else": {"else body", "The branch of an `if` run when no other one is.", -1, -1},
+	"do":   {"cond ->", "Take the condition of an `if`, `elif` or `for` and start its body.", -1, -1},
+	"end":  {"end", "Close a `define`, `if`, `for`, `test`, `assert-stack` or `assert-throws` block.", -1, -1},
+
+	"test":          {"test \"name\" ... end", "A test, run by `beremiz test` with an empty stack.", -1, -1},
+	"assert":        {"a ->", "Fail unless the value is true.", 1, 0},
+	"assert-eq":     {"actual expected ->", "Fail unless both values are equal and of the same type.", 2, 0},
+	"assert-stack":  {"assert-stack value... end", "Fail unless the stack holds exactly these values, bottom first. The stack is left as it is.", -1, -1},
+	"assert-throws": {"assert-throws body end", "Fail unless body raises an error. The stack is put back as it was before the block.", -1, -1},
+
+	"eq":    {"a b -> bool", "Check whether two values are equal.", 2, 1},
+	"neq":   {"a b -> bool", "Check whether two values are different.", 2, 1},
+	"dup":   {"a -> a a", "Duplicate the top of the stack.", 1, 2},
+	"pop":   {"a ->", "Drop the top of the stack.", 1, 0},
+	"swap":  {"a b -> b a", "Swap the top two values.", 2, 2},
+	"over":  {"a b -> a b a", "Copy the second value to the top.", 2, 3},
+	"depth": {"-> n", "Push the current stack size.", 0, 1},
+	"dump":  {"->", "Print the whole stack to stderr.", 0, 0},
+	"clear": {"... ->", "Clear the entire stack.", -1, -1},
+	"rot":   {"a b c -> b c a", "Rotate the top three values.", 3, 3},
+
+	"and": {"a b -> bool", "Check whether both values are true.", 2, 1},
+	"or":  {"a b -> bool", "Check whether either value is true.", 2, 1},
+	"not": {"", "Reserved; not implemented yet.", -1, -1},
+
+	"+":  {"a b -> a+b", "Add two numbers.", 2, 1},
+	"-":  {"a b -> a-b", "Subtract the top from the second.", 2, 1},
+	"*":  {"a b -> a*b", "Multiply two numbers.", 2, 1},
+	"/":  {"a b -> a/b", "Divide the second by the top.", 2, 1},
+	"%":  {"a b -> a%b", "The remainder of dividing the second by the top.", 2, 1},
+	"**": {"a b -> a**b", "Raise the second to the power of the top.", 2, 1},
+	"<":  {"a b -> bool", "Check whether the second is lower than the top.", 2, 1},
+	">":  {"a b -> bool", "Check whether the second is greater than the top.", 2, 1},
+	"<=": {"a b -> bool", "Check whether the second is lower than or equal to the top.", 2, 1},
+	">=": {"a b -> bool", "Check whether the second is greater than or equal to the top.", 2, 1},
+	".":  {"a b -> ab", "Concatenate two values as strings; `true`, `false` and `nil` become their names.", 2, 1},
+}
@@ -0,0 +1,249 @@
+package analysis
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Effect is how many values a piece of code takes from the stack and how
+// many it leaves in their place.
+type Effect struct {
+	In, Out int
+}
+
+// String writes e the way the README writes stack effects, naming the
+// values with letters.
+func (e Effect) String() string {
+	var in, out []string
+	for i := 0; i < e.In; i++ {
+		in = append(in, letter(i))
+	}
+	for i := 0; i < e.Out; i++ {
+		out = append(out, letter(e.In+i))
+	}
+
+	s := strings.Join(append(in, "->"), " ")
+	if len(out) > 0 {
+		s += " " + strings.Join(out, " ")
+	}
+	return s
+}
+
+func letter(i int) string {
+	if i < 26 {
+		return string(rune('a' + i))
+	}
+	return "v" + strconv.Itoa(i)
+}
+
+// Infer returns the stack effect of ts, looking defined words up in defs.
+// It returns false when the effect can't be known without running the
+// code: host words, words such as 'args' that push a varying number of
+// values, recursion, and branches or loop bodies that leave different
+// stack depths.
+func Infer(ts []tokens.Token, defs []Definition) (Effect, bool) {
+	in := inference{defs: defs, active: map[string]bool{}}
+	var s state
+	if end, ok := in.run(ts, 0, &s); !ok || end != len(ts) {
+		return Effect{}, false
+	}
+	return Effect{In: s.need, Out: s.depth}, true
+}
+
+// state is the stack as far as inference knows it: depth values above the
+// starting point, after taking need values from below it.
+type state struct {
+	depth, need int
+}
+
+func (s *state) pop(n int) {
+	if s.depth < n {
+		s.need += n - s.depth
+		s.depth = n
+	}
+	s.depth -= n
+}
+
+func (s *state) push(n int) {
+	s.depth += n
+}
+
+type inference struct {
+	defs []Definition
+	// active holds the words being inferred, to stop at recursion.
+	active map[string]bool
+}
+
+// run applies ts[i:] to s until it reaches the end of ts, or a token closing
+// or splitting the block it is in, and returns where it stopped.
+func (in *inference) run(ts []tokens.Token, i int, s *state) (int, bool) {
+	for i < len(ts) {
+		token := ts[i]
+
+		switch token.Type {
+		case tokens.Int, tokens.Float, tokens.String, tokens.Bool, tokens.Nil:
+			s.push(1)
+			i++
+
+		case tokens.EOF:
+			i++
+
+		case tokens.Elif, tokens.Else, tokens.Do, tokens.End:
+			return i, true
+
+		case tokens.Define, tokens.Test:
+			// A definition runs nothing where it is written, and tests only
+			// run under 'beremiz test'.
+			i = closing(ts, i+2) + 1
+
+		case tokens.AssertStack, tokens.AssertThrows:
+			// Both leave the stack as they found it.
+			i = closing(ts, i+1) + 1
+
+		case tokens.If:
+			var ok bool
+			if i, ok = in.branches(ts, i+1, s); !ok {
+				return i, false
+			}
+
+		case tokens.For:
+			var ok bool
+			if i, ok = in.loop(ts, i+1, s); !ok {
+				return i, false
+			}
+
+		case tokens.Identifier:
+			e, ok := in.word(token.Literal.(string))
+			if !ok {
+				return i, false
+			}
+			s.pop(e.In)
+			s.push(e.Out)
+			i++
+
+		default:
+			name, _ := Name(token)
+			b, ok := Builtins[name]
+			if !ok || b.In < 0 || b.Out < 0 {
+				return i, false
+			}
+			s.pop(b.In)
+			s.push(b.Out)
+			i++
+		}
+	}
+	return i, true
+}
+
+// condition applies a condition and the 'do' after it, returning the index
+// after the 'do'.
+func (in *inference) condition(ts []tokens.Token, i int, s *state) (int, bool) {
+	i, ok := in.run(ts, i, s)
+	if !ok || i >= len(ts) || ts[i].Type != tokens.Do {
+		return i, false
+	}
+	s.pop(1)
+	return i + 1, true
+}
+
+// branches applies an 'if' block starting at its condition. Every branch
+// must leave the same depth.
+func (in *inference) branches(ts []tokens.Token, i int, s *state) (int, bool) {
+	i, ok := in.condition(ts, i, s)
+	if !ok {
+		return i, false
+	}
+
+	// cond is the state once every condition so far was false.
+	cond := *s
+	var ends []state
+
+	for {
+		branch := cond
+		if i, ok = in.run(ts, i, &branch); !ok || i >= len(ts) {
+			return i, false
+		}
+		ends = append(ends, branch)
+
+		switch ts[i].Type {
+		case tokens.Elif:
+			if i, ok = in.condition(ts, i+1, &cond); !ok {
+				return i, false
+			}
+			continue
+
+		case tokens.Else:
+			branch := cond
+			if i, ok = in.run(ts, i+1, &branch); !ok || i >= len(ts) || ts[i].Type != tokens.End {
+				return i, false
+			}
+			ends = append(ends, branch)
+
+		case tokens.End:
+			// Without an 'else', nothing runs when every condition is
+			// false.
+			ends = append(ends, cond)
+
+		default:
+			return i, false
+		}
+		break
+	}
+
+	*s = ends[0]
+	for _, end := range ends[1:] {
+		if end.depth-end.need != s.depth-s.need {
+			return i, false
+		}
+		if end.need > s.need {
+			s.depth += end.need - s.need
+			s.need = end.need
+		}
+	}
+	return i + 1, true
+}
+
+// loop applies a 'for' block starting at its condition. A round of the loop
+// must leave the depth it started with.
+func (in *inference) loop(ts []tokens.Token, i int, s *state) (int, bool) {
+	start := *s
+	i, ok := in.condition(ts, i, s)
+	if !ok {
+		return i, false
+	}
+	exit := *s
+
+	if i, ok = in.run(ts, i, s); !ok || i >= len(ts) || ts[i].Type != tokens.End {
+		return i, false
+	}
+	if s.depth-s.need != start.depth-start.need {
+		return i, false
+	}
+
+	round := *s
+	*s = exit
+	if round.need > s.need {
+		s.depth += round.need - s.need
+		s.need = round.need
+	}
+	return i + 1, true
+}
+
+// word returns the effect of calling a defined word.
+func (in *inference) word(name string) (Effect, bool) {
+	def, ok := Lookup(in.defs, name)
+	if !ok || in.active[name] {
+		return Effect{}, false
+	}
+
+	in.active[name] = true
+	defer delete(in.active, name)
+
+	var s state
+	if end, ok := in.run(def.Body, 0, &s); !ok || end != len(def.Body) {
+		return Effect{}, false
+	}
+	return Effect{In: s.need, Out: s.depth}, true
+}
@@ -0,0 +1,255 @@
+// Package coverage records which parts of a Beremiz program ran: every
+// instruction, every line, and each branch of the 'if' and 'for' blocks.
+package coverage
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"slices"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Point is an instruction written in the program.
+type Point struct {
+	Line  int `json:"line"`
+	Col   int `json:"col"`
+	Len   int `json:"len"`
+	Count int `json:"count"`
+}
+
+// Branch is one way through an 'if' or 'for' block: the body after an
+// 'if ... do', an 'elif ... do' or an 'else', the body of a loop, or the
+// way around a body when its condition fails.
+type Branch struct {
+	// Kind is the keyword starting the branch: "if", "elif", "else" or
+	// "for".
+	Kind string `json:"kind"`
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+	// Skipped marks the way around the body: to the next 'elif', past the
+	// block, or out of the loop. A condition followed by an 'else' has no
+	// such branch; the 'else' is its way around.
+	Skipped bool `json:"skipped,omitempty"`
+	// Count is how many times the body was entered; for a loop, how many
+	// rounds ran. For a skipped branch, how many times the condition failed.
+	Count int `json:"count"`
+}
+
+// name returns how b is shown in reports, e.g. "if" or "if skipped".
+func (b *Branch) name() string {
+	if b.Skipped {
+		return b.Kind + " skipped"
+	}
+	return b.Kind
+}
+
+// condition is a 'do' and where the program goes after it.
+type condition struct {
+	// body is where the program goes when the condition holds, and skip
+	// where it goes when it doesn't.
+	body, skip tokens.Loc
+	branch     *Branch
+	// skipped is the branch taken when the condition fails: the 'else'
+	// after it, or the way around the body.
+	skipped *Branch
+}
+
+// Coverage is a beremiz.Hook recording what runs of one file.
+type Coverage struct {
+	File   string
+	source string
+
+	Points   []*Point
+	Branches []*Branch
+
+	points     map[tokens.Loc]*Point
+	conditions map[tokens.Loc]*condition
+	// pending is the condition just decided, until the next instruction
+	// shows which way it went.
+	pending *condition
+}
+
+// New returns a Coverage for the program in source, reported by the
+// interpreter as file.
+func New(file, source string) *Coverage {
+	c := &Coverage{
+		File:       file,
+		source:     source,
+		points:     map[tokens.Loc]*Point{},
+		conditions: map[tokens.Loc]*condition{},
+	}
+
+	ts, _ := lexer.New(source, file).Tokenize()
+	// Checking the blocks sets the JmpTo of each 'do' to where the program
+	// goes when its condition fails.
+	parser.New(ts, false).Check()
+
+	// arms holds the branch each open block is in, or nil for a 'define',
+	// a test or an assertion block, or after an 'else'.
+	var arms []*Branch
+	// compared is the 'end' of the last 'assert-stack': the values before
+	// it are compared with the stack rather than run.
+	var compared int
+
+	for i, token := range ts {
+		switch token.Type {
+		case tokens.If, tokens.For:
+			arms = append(arms, &Branch{Kind: keyword(token), Line: token.Loc.Line, Col: token.Loc.Col})
+		case tokens.Define, tokens.Test, tokens.AssertThrows:
+			arms = append(arms, nil)
+		case tokens.AssertStack:
+			arms = append(arms, nil)
+			compared = token.JmpTo - 1
+		case tokens.Elif:
+			if len(arms) > 0 {
+				arms[len(arms)-1] = &Branch{Kind: "elif", Line: token.Loc.Line, Col: token.Loc.Col}
+			}
+		case tokens.Else:
+			if len(arms) > 0 {
+				arms[len(arms)-1] = nil
+			}
+		case tokens.End:
+			if len(arms) > 0 {
+				arms = arms[:len(arms)-1]
+			}
+		}
+
+		if !coverable(ts, i) || token.Type != tokens.AssertStack && i < compared {
+			continue
+		}
+		p := &Point{Line: token.Loc.Line, Col: token.Loc.Col, Len: max(token.Len, 1)}
+		c.Points = append(c.Points, p)
+		c.points[token.Loc] = p
+
+		if token.Type == tokens.Do && len(arms) > 0 && arms[len(arms)-1] != nil && i+1 < len(ts) {
+			branch := arms[len(arms)-1]
+			c.Branches = append(c.Branches, branch)
+
+			cond := &condition{body: ts[i+1].Loc, branch: branch}
+			if token.JmpTo > 0 && token.JmpTo < len(ts) {
+				cond.skip = ts[token.JmpTo].Loc
+			}
+			c.conditions[token.Loc] = cond
+		}
+	}
+
+	// An 'else' is entered when the condition before it fails, so it is
+	// found from that condition.
+	for i, token := range ts {
+		if token.Type != tokens.Else || i+1 >= len(ts) {
+			continue
+		}
+		branch := &Branch{Kind: "else", Line: token.Loc.Line, Col: token.Loc.Col}
+		for _, cond := range c.conditions {
+			if cond.skip == ts[i+1].Loc {
+				cond.skipped = branch
+				c.Branches = append(c.Branches, branch)
+				break
+			}
+		}
+	}
+	for _, cond := range c.conditions {
+		if cond.skipped == nil {
+			cond.skipped = &Branch{Kind: cond.branch.Kind, Line: cond.branch.Line, Col: cond.branch.Col, Skipped: true}
+			c.Branches = append(c.Branches, cond.skipped)
+		}
+	}
+
+	slices.SortFunc(c.Branches, func(a, b *Branch) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Col, b.Col), skippedLast(a, b))
+	})
+	return c
+}
+
+// skippedLast orders a branch before the way around it.
+func skippedLast(a, b *Branch) int {
+	switch {
+	case a.Skipped == b.Skipped:
+		return 0
+	case a.Skipped:
+		return 1
+	}
+	return -1
+}
+
+// coverable reports whether ts[i] is an instruction that runs, rather than
+// part of how blocks are written: 'define' and 'test' and the name after
+// them, and the 'elif', 'else' and 'end' keywords, which only jump.
+func coverable(ts []tokens.Token, i int) bool {
+	switch ts[i].Type {
+	case tokens.Define, tokens.Test, tokens.Elif, tokens.Else, tokens.End, tokens.EOF:
+		return false
+	}
+	return i == 0 || ts[i-1].Type != tokens.Define && ts[i-1].Type != tokens.Test
+}
+
+func keyword(token tokens.Token) string {
+	name, _ := token.Literal.(string)
+	return name
+}
+
+func (c *Coverage) Before(ev *beremiz.Event) error {
+	if ev.Loc.File != c.File {
+		return nil
+	}
+
+	c.settle(ev.Loc)
+
+	if ev.Kind == beremiz.Return {
+		return nil
+	}
+	if p, ok := c.points[ev.Loc]; ok {
+		p.Count++
+	}
+	return nil
+}
+
+// settle counts the branch the pending condition took, now that the program
+// went on to loc.
+func (c *Coverage) settle(loc tokens.Loc) {
+	cond := c.pending
+	if cond == nil {
+		return
+	}
+	c.pending = nil
+	if loc == cond.body {
+		cond.branch.Count++
+		return
+	}
+	// The program is usually at cond.skip now. When the block ends a word
+	// it is at the word's Return instead, and when the block ends the
+	// program nothing runs after it at all (see Done).
+	cond.skipped.Count++
+}
+
+// Done counts the branch of a condition decided by the last instruction of
+// the program, which no later instruction shows. Call it once the program
+// stopped, before writing any report.
+func (c *Coverage) Done() {
+	c.settle(tokens.Loc{})
+}
+
+func (c *Coverage) After(ev *beremiz.Event) error {
+	if ev.Kind == beremiz.Instruction && ev.Loc.File == c.File {
+		if cond, ok := c.conditions[ev.Loc]; ok && ev.Word == "do" {
+			c.pending = cond
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes what was recorded, for other tools.
+func (c *Coverage) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{
+		"file":     c.File,
+		"points":   c.Points,
+		"branches": c.Branches,
+	})
+}
@@ -0,0 +1,69 @@
+package coverage_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/coverage"
+)
+
+func TestBranches(t *testing.T) {
+	source := `define abs
+    if dup 0 < do -1 * end
+end
+3 abs
+if true do 1 elif false do 2 end
+for false do 1 end
+if 1 2 < do "a" else "b" end
+if false do 9 end`
+
+	c := coverage.New("branches.brz", source)
+	if _, e := beremiz.New(beremiz.WithHook(c)).EvalSource(context.Background(), "branches.brz", source); e != nil {
+		t.Fatalf("EvalSource: %v", e)
+	}
+	c.Done()
+
+	want := []coverage.Branch{
+		// The way around a body at the end of a word, and at the end of
+		// the program, is counted too.
+		{Kind: "if", Line: 2, Col: 5, Count: 0},
+		{Kind: "if", Line: 2, Col: 5, Skipped: true, Count: 1},
+		{Kind: "if", Line: 5, Col: 1, Count: 1},
+		{Kind: "if", Line: 5, Col: 1, Skipped: true, Count: 0},
+		{Kind: "elif", Line: 5, Col: 14, Count: 0},
+		{Kind: "elif", Line: 5, Col: 14, Skipped: true, Count: 0},
+		// A loop that ran no rounds.
+		{Kind: "for", Line: 6, Col: 1, Count: 0},
+		{Kind: "for", Line: 6, Col: 1, Skipped: true, Count: 1},
+		{Kind: "if", Line: 7, Col: 1, Count: 1},
+		{Kind: "else", Line: 7, Col: 17, Count: 0},
+		{Kind: "if", Line: 8, Col: 1, Count: 0},
+		{Kind: "if", Line: 8, Col: 1, Skipped: true, Count: 1},
+	}
+	var got []coverage.Branch
+	for _, b := range c.Branches {
+		got = append(got, *b)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("branches =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestLoopRounds(t *testing.T) {
+	source := "0 for dup 3 < do 1 + end"
+
+	c := coverage.New("loop.brz", source)
+	if _, e := beremiz.New(beremiz.WithHook(c)).EvalSource(context.Background(), "loop.brz", source); e != nil {
+		t.Fatalf("EvalSource: %v", e)
+	}
+	c.Done()
+
+	if len(c.Branches) != 2 {
+		t.Fatalf("got %d branches, want the loop and the way out of it", len(c.Branches))
+	}
+	if c.Branches[0].Count != 3 || c.Branches[1].Count != 1 {
+		t.Errorf("branches = %+v, %+v; want 3 rounds and 1 exit", *c.Branches[0], *c.Branches[1])
+	}
+}
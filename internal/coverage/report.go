@@ -0,0 +1,187 @@
+package coverage
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// lines returns how many times each line with instructions ran: the most
+// any of its instructions ran.
+func (c *Coverage) lines() map[int]int {
+	lines := map[int]int{}
+	for _, p := range c.Points {
+		lines[p.Line] = max(lines[p.Line], p.Count)
+	}
+	return lines
+}
+
+// percent returns covered out of total as a percentage, or 100 when there
+// is nothing to cover.
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(covered) * 100 / float64(total)
+}
+
+func count[T any](items []T, covered func(T) bool) int {
+	n := 0
+	for _, item := range items {
+		if covered(item) {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary writes the share of lines, instructions and branches that ran,
+// then the lines and branches that never did.
+func (c *Coverage) Summary(w io.Writer) {
+	lines := c.lines()
+	var missed []int
+	for n := 1; n <= strings.Count(c.source, "\n")+1; n++ {
+		if count, ok := lines[n]; ok && count == 0 {
+			missed = append(missed, n)
+		}
+	}
+
+	points := count(c.Points, func(p *Point) bool { return p.Count > 0 })
+	branches := count(c.Branches, func(b *Branch) bool { return b.Count > 0 })
+
+	fmt.Fprintf(w, "Coverage of %s\n", c.File)
+	fmt.Fprintf(w, "  Lines:         %4d/%-4d %5.1f%%\n", len(lines)-len(missed), len(lines), percent(len(lines)-len(missed), len(lines)))
+	fmt.Fprintf(w, "  Instructions:  %4d/%-4d %5.1f%%\n", points, len(c.Points), percent(points, len(c.Points)))
+	fmt.Fprintf(w, "  Branches:      %4d/%-4d %5.1f%%\n", branches, len(c.Branches), percent(branches, len(c.Branches)))
+
+	if len(missed) > 0 {
+		fmt.Fprintf(w, "Lines not run: %s\n", ranges(missed))
+	}
+	for _, b := range c.Branches {
+		if b.Count == 0 {
+			fmt.Fprintf(w, "Branch not taken: %s:%d:%d %s\n", c.File, b.Line, b.Col, b.name())
+		}
+	}
+}
+
+// ranges writes sorted numbers compactly, e.g. "3-5, 9".
+func ranges(ns []int) string {
+	var parts []string
+	for i := 0; i < len(ns); {
+		j := i
+		for j+1 < len(ns) && ns[j+1] == ns[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(ns[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", ns[i], ns[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteHTML writes the source as a web page, with each instruction marked
+// as run or not and each line and branch showing how often it ran.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	type mark struct {
+		end   int
+		count int
+	}
+	// marks holds, per line, the instructions starting at each column.
+	marks := map[int]map[int]mark{}
+	for _, p := range c.Points {
+		if marks[p.Line] == nil {
+			marks[p.Line] = map[int]mark{}
+		}
+		marks[p.Line][p.Col] = mark{end: p.Col + p.Len, count: p.Count}
+	}
+	branches := map[int][]*Branch{}
+	for _, b := range c.Branches {
+		branches[b.Line] = append(branches[b.Line], b)
+	}
+	counts := c.lines()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, htmlHeader, html.EscapeString(c.File))
+	c.Summary(&htmlEscaper{&b})
+	b.WriteString("</pre>\n<table>\n")
+
+	for i, line := range strings.Split(c.source, "\n") {
+		n := i + 1
+		runes := []rune(strings.TrimSuffix(line, "\r"))
+
+		class, hits := "", ""
+		if count, ok := counts[n]; ok {
+			class, hits = "miss", "0"
+			if count > 0 {
+				class, hits = "hit", fmt.Sprint(count)
+			}
+		}
+
+		var code strings.Builder
+		for col := 1; col <= len(runes); {
+			m, ok := marks[n][col]
+			if !ok {
+				code.WriteString(html.EscapeString(string(runes[col-1])))
+				col++
+				continue
+			}
+			end := min(m.end, len(runes)+1)
+			tokenClass := "miss"
+			if m.count > 0 {
+				tokenClass = "hit"
+			}
+			fmt.Fprintf(&code, `<span class="%s" title="ran: %d">%s</span>`,
+				tokenClass, m.count, html.EscapeString(string(runes[col-1:end-1])))
+			col = end
+		}
+
+		var notes []string
+		for _, br := range branches[n] {
+			notes = append(notes, fmt.Sprintf("%s: %d", br.name(), br.Count))
+		}
+
+		fmt.Fprintf(&b, "<tr class=\"%s\"><td class=\"n\">%d</td><td class=\"c\">%s</td><td><pre>%s</pre></td><td class=\"b\">%s</td></tr>\n",
+			class, n, hits, code.String(), html.EscapeString(strings.Join(notes, ", ")))
+	}
+
+	b.WriteString("</table>\n</body>\n</html>\n")
+	_, e := io.WriteString(w, b.String())
+	return e
+}
+
+// htmlEscaper escapes what is written through it.
+type htmlEscaper struct {
+	w io.Writer
+}
+
+func (h *htmlEscaper) Write(p []byte) (int, error) {
+	if _, e := io.WriteString(h.w, html.EscapeString(string(p))); e != nil {
+		return 0, e
+	}
+	return len(p), nil
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage of %s</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 0.5em; vertical-align: top; }
+pre { margin: 0; }
+td.n, td.c { color: #888; text-align: right; }
+td.b { color: #555; font-size: 0.9em; }
+tr.miss td.c { color: #c00; }
+span.hit { background: #d4f7d4; }
+span.miss { background: #f9d0d0; }
+</style>
+</head>
+<body>
+<pre>
+`
@@ -0,0 +1,640 @@
+// Package dap serves the Debug Adapter Protocol, so editors such as VS Code
+// can debug Beremiz programs. One Server debugs one program, started by a
+// launch request, with the same breakpoints and stepping as the command-line
+// debugger.
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/analysis"
+	"github.com/adaiasmagdiel/beremiz-go/internal/debug"
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+	"github.com/adaiasmagdiel/beremiz-go/internal/wire"
+)
+
+// threadID is the only thread: Beremiz programs run on one.
+const threadID = 1
+
+// Variable references of the scopes. Locals are per frame, starting at
+// localsRef.
+const (
+	stackRef   = 1
+	globalsRef = 2
+	localsRef  = 1000
+)
+
+// Server is a debug adapter talking to one client.
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	opts []beremiz.Option
+
+	writeMu sync.Mutex
+	seq     int
+
+	// mu guards everything below, which the program's goroutine shares
+	// with the one serving requests.
+	mu  sync.Mutex
+	ctl *debug.Controller
+	// paused is the event the program is paused at, or nil while it runs.
+	paused *beremiz.Event
+	resume chan error
+	// marks holds the stack length when each active word was called, so
+	// the values above it can be shown as the word's locals.
+	marks []int
+	quit  bool
+
+	program    string
+	source     string
+	args       []string
+	noDebug    bool
+	launched   bool
+	configured bool
+	started    bool
+}
+
+// New returns a Server reading requests from in and writing to out. opts
+// configure the interpreter running the program.
+func New(in io.Reader, out io.Writer, opts ...beremiz.Option) *Server {
+	return &Server{
+		in:     bufio.NewReader(in),
+		out:    out,
+		opts:   opts,
+		ctl:    debug.NewController(),
+		resume: make(chan error),
+	}
+}
+
+// Serve handles requests until the client disconnects or in ends.
+func (s *Server) Serve() error {
+	for {
+		body, e := wire.Read(s.in)
+		if errors.Is(e, io.EOF) {
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+
+		var req request
+		if e := json.Unmarshal(body, &req); e != nil {
+			return fmt.Errorf("invalid message: %v", e)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		if done := s.handle(req); done {
+			return nil
+		}
+	}
+}
+
+func (s *Server) send(v any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.seq++
+	switch m := v.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+	wire.Write(s.out, v)
+}
+
+func (s *Server) respond(req request, body any) {
+	s.send(&response{Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body})
+}
+
+func (s *Server) fail(req request, format string, args ...any) {
+	s.send(&response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Command:    req.Command,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+func (s *Server) event(name string, body any) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// handle answers one request. It returns true when the session is over.
+func (s *Server) handle(req request) bool {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsFunctionBreakpoints":      true,
+			"supportsTerminateRequest":         true,
+		})
+		s.event("initialized", nil)
+
+	case "launch":
+		s.launch(req)
+
+	case "setBreakpoints":
+		s.setBreakpoints(req)
+
+	case "setFunctionBreakpoints":
+		s.setFunctionBreakpoints(req)
+
+	case "setExceptionBreakpoints":
+		s.respond(req, map[string]any{"breakpoints": []any{}})
+
+	case "configurationDone":
+		s.respond(req, nil)
+		s.mu.Lock()
+		s.configured = true
+		s.mu.Unlock()
+		s.start()
+
+	case "threads":
+		s.respond(req, map[string]any{
+			"threads": []map[string]any{{"id": threadID, "name": "main"}},
+		})
+
+	case "stackTrace":
+		s.stackTrace(req)
+
+	case "scopes":
+		s.scopes(req)
+
+	case "variables":
+		s.variables(req)
+
+	case "evaluate":
+		s.evaluate(req)
+
+	case "continue", "next", "stepIn", "stepOut":
+		s.step(req)
+
+	case "pause":
+		s.mu.Lock()
+		s.ctl.Pause()
+		s.mu.Unlock()
+		s.respond(req, nil)
+
+	case "disconnect", "terminate":
+		s.respond(req, nil)
+		s.stop()
+		return req.Command == "disconnect"
+
+	default:
+		s.fail(req, "Unsupported request '%s'.", req.Command)
+	}
+
+	return false
+}
+
+func (s *Server) launch(req request) {
+	var args struct {
+		Program     string   `json:"program"`
+		Args        []string `json:"args"`
+		StopOnEntry bool     `json:"stopOnEntry"`
+		NoDebug     bool     `json:"noDebug"`
+	}
+	if e := json.Unmarshal(req.Arguments, &args); e != nil || args.Program == "" {
+		s.fail(req, "The launch request needs a 'program' to run.")
+		return
+	}
+
+	source, e := os.ReadFile(args.Program)
+	if e != nil {
+		s.fail(req, "Unable to read '%s': %v.", args.Program, e)
+		return
+	}
+
+	s.mu.Lock()
+	s.program = args.Program
+	s.source = string(source)
+	s.args = args.Args
+	s.noDebug = args.NoDebug
+	s.launched = true
+	if !args.StopOnEntry {
+		s.ctl.Continue()
+	}
+	s.mu.Unlock()
+
+	s.respond(req, nil)
+	s.start()
+}
+
+// start runs the program once it was launched and configured.
+func (s *Server) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.launched || !s.configured || s.started {
+		return
+	}
+	s.started = true
+
+	go s.run()
+}
+
+// run runs the program and reports how it ended.
+func (s *Server) run() {
+	interp := beremiz.New(append(s.opts,
+		beremiz.WithArgs(s.args...),
+		beremiz.WithStdin(strings.NewReader("")),
+		beremiz.WithStdout(output{s, "stdout"}),
+		beremiz.WithStderr(output{s, "stderr"}),
+		beremiz.WithHook(s),
+	)...)
+
+	_, e := interp.EvalSource(context.Background(), s.program, s.source)
+
+	code := 0
+	var exit *beremiz.ExitError
+	switch {
+	case errors.As(e, &exit):
+		code = exit.Code
+	case errors.Is(e, debug.ErrQuit):
+		code = 1
+	case e != nil:
+		code = 1
+		var buf bytes.Buffer
+		renderer := err.NewRenderer(&buf, err.FormatHuman, false)
+		if list := err.Diagnostics(e); len(list) > 0 {
+			renderer.RenderAll(list, s.source, false)
+		} else {
+			renderer.Message(err.SeverityError, e.Error())
+		}
+		s.event("output", map[string]any{"category": "stderr", "output": buf.String()})
+	}
+
+	s.event("exited", map[string]any{"exitCode": code})
+	s.event("terminated", nil)
+}
+
+// stop ends the program, whether it is paused or running.
+func (s *Server) stop() {
+	s.mu.Lock()
+	s.quit = true
+	paused := s.paused != nil
+	s.paused = nil
+	s.mu.Unlock()
+
+	if paused {
+		s.resume <- debug.ErrQuit
+	}
+}
+
+// Before pauses the program where the controller says, and waits for the
+// client to resume it.
+func (s *Server) Before(ev *beremiz.Event) error {
+	s.mu.Lock()
+	if s.quit {
+		s.mu.Unlock()
+		return debug.ErrQuit
+	}
+
+	// An error caught by 'assert-throws' ends the calls inside the block
+	// without their Return events.
+	s.marks = s.marks[:min(len(s.marks), len(ev.Frames))]
+	switch ev.Kind {
+	case beremiz.Call:
+		s.marks = append(s.marks, len(ev.Stack()))
+	case beremiz.Return:
+		s.marks = s.marks[:len(s.marks)-1]
+	}
+
+	pause, reason := s.ctl.Check(ev)
+	if s.noDebug {
+		pause = false
+	}
+	if pause {
+		s.paused = ev
+	}
+	s.mu.Unlock()
+
+	if !pause {
+		return nil
+	}
+
+	ev.Flush()
+	s.event("stopped", map[string]any{
+		"reason":            string(reason),
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	})
+	return <-s.resume
+}
+
+func (s *Server) After(ev *beremiz.Event) error {
+	return nil
+}
+
+func (s *Server) step(req request) {
+	s.mu.Lock()
+	ev := s.paused
+	if ev == nil {
+		s.mu.Unlock()
+		s.fail(req, "The program is not paused.")
+		return
+	}
+
+	switch req.Command {
+	case "continue":
+		s.ctl.Continue()
+	case "next":
+		s.ctl.Next(ev)
+	case "stepIn":
+		s.ctl.Step()
+	case "stepOut":
+		if !s.ctl.Out(ev) {
+			s.ctl.Continue()
+		}
+	}
+	s.paused = nil
+	s.mu.Unlock()
+
+	if req.Command == "continue" {
+		s.respond(req, map[string]any{"allThreadsContinued": true})
+	} else {
+		s.respond(req, nil)
+	}
+	s.resume <- nil
+}
+
+func (s *Server) setBreakpoints(req request) {
+	var args struct {
+		Source struct {
+			Path string `json:"path"`
+		} `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}
+	if e := json.Unmarshal(req.Arguments, &args); e != nil {
+		s.fail(req, "Invalid arguments: %v.", e)
+		return
+	}
+
+	lines := codeLines(args.Source.Path)
+
+	s.mu.Lock()
+	s.ctl.ClearLines(args.Source.Path)
+	var result []map[string]any
+	for _, want := range args.Breakpoints {
+		b := s.ctl.Add(debug.Breakpoint{File: args.Source.Path, Line: want.Line})
+		result = append(result, map[string]any{
+			"id":       b.ID,
+			"verified": lines[want.Line],
+			"line":     want.Line,
+		})
+	}
+	s.mu.Unlock()
+
+	s.respond(req, map[string]any{"breakpoints": result})
+}
+
+// codeLines returns the lines of the file at path that have code on them,
+// where a breakpoint can stop.
+func codeLines(path string) map[int]bool {
+	lines := map[int]bool{}
+
+	source, e := os.ReadFile(path)
+	if e != nil {
+		return lines
+	}
+
+	ts, _ := lexer.New(string(source), path).Tokenize()
+	for _, t := range ts {
+		if t.Type != tokens.EOF {
+			lines[t.Loc.Line] = true
+		}
+	}
+	return lines
+}
+
+func (s *Server) setFunctionBreakpoints(req request) {
+	var args struct {
+		Breakpoints []struct {
+			Name string `json:"name"`
+		} `json:"breakpoints"`
+	}
+	if e := json.Unmarshal(req.Arguments, &args); e != nil {
+		s.fail(req, "Invalid arguments: %v.", e)
+		return
+	}
+
+	s.mu.Lock()
+	s.ctl.ClearWords()
+	var result []map[string]any
+	for _, want := range args.Breakpoints {
+		b := s.ctl.Add(debug.Breakpoint{Word: want.Name})
+		result = append(result, map[string]any{"id": b.ID, "verified": true})
+	}
+	s.mu.Unlock()
+
+	s.respond(req, map[string]any{"breakpoints": result})
+}
+
+// frame is a stack frame as the client sees it. Frame 0 is the innermost.
+type frame struct {
+	name string
+	loc  beremiz.Loc
+	// mark is the stack length when the word was called, or -1 for the
+	// main program.
+	mark int
+}
+
+// frames returns the frames of the paused program, innermost first. The
+// caller holds s.mu.
+func (s *Server) frames() []frame {
+	ev := s.paused
+	if ev == nil {
+		return nil
+	}
+
+	var frames []frame
+	loc := ev.Loc
+	for i := len(ev.Frames) - 1; i >= 0; i-- {
+		frames = append(frames, frame{name: ev.Frames[i].Name, loc: loc, mark: s.marks[i]})
+		loc = ev.Frames[i].Loc
+	}
+	return append(frames, frame{name: "<main>", loc: loc, mark: -1})
+}
+
+func (s *Server) stackTrace(req request) {
+	s.mu.Lock()
+	frames := s.frames()
+	s.mu.Unlock()
+
+	var result []map[string]any
+	for i, f := range frames {
+		result = append(result, map[string]any{
+			"id":     i + 1,
+			"name":   f.name,
+			"line":   f.loc.Line,
+			"column": f.loc.Col,
+			"source": map[string]any{"name": filepath.Base(f.loc.File), "path": f.loc.File},
+		})
+	}
+
+	s.respond(req, map[string]any{"stackFrames": result, "totalFrames": len(result)})
+}
+
+func (s *Server) scopes(req request) {
+	var args struct {
+		FrameID int `json:"frameId"`
+	}
+	json.Unmarshal(req.Arguments, &args)
+
+	s.mu.Lock()
+	frames := s.frames()
+	s.mu.Unlock()
+
+	scopes := []map[string]any{
+		{"name": "Stack", "variablesReference": stackRef, "expensive": false},
+	}
+	if i := args.FrameID - 1; i >= 0 && i < len(frames) && frames[i].mark >= 0 {
+		scopes = append(scopes, map[string]any{
+			"name": "Locals", "variablesReference": localsRef + i, "expensive": false,
+		})
+	}
+	scopes = append(scopes, map[string]any{
+		"name": "Globals", "variablesReference": globalsRef, "expensive": false,
+	})
+
+	s.respond(req, map[string]any{"scopes": scopes})
+}
+
+func (s *Server) variables(req request) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	json.Unmarshal(req.Arguments, &args)
+
+	s.mu.Lock()
+	ev := s.paused
+	frames := s.frames()
+	source := s.source
+	s.mu.Unlock()
+
+	result := []map[string]any{}
+	if ev == nil {
+		s.respond(req, map[string]any{"variables": result})
+		return
+	}
+
+	stack := ev.Stack()
+
+	switch ref := args.VariablesReference; {
+	case ref == stackRef:
+		result = stackVariables(stack, 0)
+
+	case ref == globalsRef:
+		ts, _ := lexer.New(source, "").Tokenize()
+		for _, def := range analysis.Definitions(ts) {
+			body := make([]string, len(def.Body))
+			for i, t := range def.Body {
+				body[i] = text(t)
+			}
+
+			result = append(result, map[string]any{
+				"name":               def.Name,
+				"value":              strings.Join(body, " "),
+				"type":               "word",
+				"variablesReference": 0,
+			})
+		}
+
+	case ref >= localsRef && ref-localsRef < len(frames):
+		mark := frames[ref-localsRef].mark
+		if mark >= 0 && mark <= len(stack) {
+			result = stackVariables(stack[mark:], mark)
+		}
+	}
+
+	s.respond(req, map[string]any{"variables": result})
+}
+
+// stackVariables lists values, the first of which is at index first of the
+// stack, top last.
+func stackVariables(values []any, first int) []map[string]any {
+	result := []map[string]any{}
+	for i, v := range values {
+		result = append(result, map[string]any{
+			"name":               fmt.Sprintf("[%d]", first+i),
+			"value":              quote(v),
+			"type":               debug.TypeName(v),
+			"variablesReference": 0,
+		})
+	}
+	return result
+}
+
+func (s *Server) evaluate(req request) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	json.Unmarshal(req.Arguments, &args)
+
+	s.mu.Lock()
+	ev := s.paused
+	s.mu.Unlock()
+
+	if ev == nil {
+		s.fail(req, "The program is not paused.")
+		return
+	}
+
+	stack, e := ev.Eval(context.Background(), args.Expression)
+	ev.Flush()
+	if e != nil {
+		s.fail(req, "%v", e)
+		return
+	}
+
+	values := make([]string, len(stack))
+	for i, v := range stack {
+		values[i] = quote(v)
+	}
+	s.respond(req, map[string]any{"result": strings.Join(values, " "), "variablesReference": 0})
+}
+
+// quote formats v as it would be written in the source.
+func quote(v any) string {
+	if str, ok := v.(string); ok {
+		return fmt.Sprintf("%q", str)
+	}
+	return debug.FormatValue(v)
+}
+
+// text returns token as it is written in the source.
+func text(token tokens.Token) string {
+	if token.Type == tokens.String {
+		return fmt.Sprintf("%q", token.Literal)
+	}
+	return debug.FormatValue(token.Literal)
+}
+
+// output sends what the program prints to the client.
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o output) Write(p []byte) (int, error) {
+	o.s.event("output", map[string]any{"category": o.category, "output": string(p)})
+	return len(p), nil
+}
@@ -0,0 +1,227 @@
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/dap"
+	"github.com/adaiasmagdiel/beremiz-go/internal/wire"
+)
+
+// client drives a Server over pipes, the way an editor would.
+type client struct {
+	t    *testing.T
+	in   io.Writer
+	msgs chan map[string]any
+	seq  int
+	// output collects what the program printed.
+	output string
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- dap.New(inR, outW).Serve()
+		outW.Close()
+	}()
+
+	c := &client{t: t, in: inW, msgs: make(chan map[string]any, 64)}
+	go func() {
+		r := bufio.NewReader(outR)
+		for {
+			body, e := wire.Read(r)
+			if e != nil {
+				close(c.msgs)
+				return
+			}
+			var msg map[string]any
+			json.Unmarshal(body, &msg)
+			c.msgs <- msg
+		}
+	}()
+
+	t.Cleanup(func() {
+		inW.Close()
+		select {
+		case e := <-done:
+			if e != nil {
+				t.Errorf("Serve: %v", e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("Serve didn't return")
+		}
+	})
+	return c
+}
+
+// send sends a request and returns its response.
+func (c *client) send(command string, args any) map[string]any {
+	c.t.Helper()
+	c.seq++
+	wire.Write(c.in, map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+
+	resp := c.wait("response", command)
+	if resp["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, resp["message"])
+	}
+	return resp
+}
+
+// wait returns the next message of type kind ("response" or "event")
+// named name, skipping the others. Output events are collected on the way.
+func (c *client) wait(kind, name string) map[string]any {
+	c.t.Helper()
+	for {
+		select {
+		case msg, ok := <-c.msgs:
+			if !ok {
+				c.t.Fatalf("the server stopped before sending the %s '%s'", kind, name)
+			}
+			if msg["type"] == "event" && msg["event"] == "output" {
+				c.output += body(msg)["output"].(string)
+			}
+			if msg["type"] == kind && (msg["command"] == name || msg["event"] == name) {
+				return msg
+			}
+		case <-time.After(5 * time.Second):
+			c.t.Fatalf("timed out waiting for the %s '%s'", kind, name)
+		}
+	}
+}
+
+func body(msg map[string]any) map[string]any {
+	b, _ := msg["body"].(map[string]any)
+	return b
+}
+
+// list returns the field key of the body of msg, an array of objects.
+func list(msg map[string]any, key string) []map[string]any {
+	var items []map[string]any
+	for _, item := range body(msg)[key].([]any) {
+		items = append(items, item.(map[string]any))
+	}
+	return items
+}
+
+func TestSession(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "square.brz")
+	source := "define square\n    dup *\nend\n3 square writeln\n"
+	if e := os.WriteFile(program, []byte(source), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	c := newClient(t)
+
+	init := c.send("initialize", map[string]any{"adapterID": "beremiz"})
+	if body(init)["supportsConfigurationDoneRequest"] != true {
+		t.Errorf("initialize = %v, want configurationDone supported", body(init))
+	}
+	c.wait("event", "initialized")
+
+	c.send("launch", map[string]any{"program": program})
+
+	bps := list(c.send("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": program},
+		"breakpoints": []map[string]any{{"line": 2}, {"line": 3}},
+	}), "breakpoints")
+	if len(bps) != 2 || bps[0]["verified"] != true || bps[1]["verified"] != true {
+		t.Errorf("breakpoints = %v, want both verified", bps)
+	}
+
+	c.send("configurationDone", nil)
+
+	stopped := c.wait("event", "stopped")
+	if reason := body(stopped)["reason"]; reason != "breakpoint" {
+		t.Errorf("stopped for %v, want breakpoint", reason)
+	}
+
+	frames := list(c.send("stackTrace", map[string]any{"threadId": 1}), "stackFrames")
+	if len(frames) != 2 {
+		t.Fatalf("stackFrames = %v, want 2", frames)
+	}
+	for i, want := range []struct {
+		name string
+		line float64
+	}{{"square", 2}, {"<main>", 4}} {
+		if frames[i]["name"] != want.name || frames[i]["line"] != want.line {
+			t.Errorf("frame %d = %v at line %v, want %s at line %v", i, frames[i]["name"], frames[i]["line"], want.name, want.line)
+		}
+	}
+
+	stack := list(c.send("variables", map[string]any{"variablesReference": 1}), "variables")
+	if len(stack) != 1 || stack[0]["value"] != "3" {
+		t.Errorf("stack = %v, want [3]", stack)
+	}
+	globals := list(c.send("variables", map[string]any{"variablesReference": 2}), "variables")
+	if len(globals) != 1 || globals[0]["name"] != "square" || globals[0]["value"] != "dup *" {
+		t.Errorf("globals = %v, want square = dup *", globals)
+	}
+
+	c.send("continue", map[string]any{"threadId": 1})
+
+	exited := c.wait("event", "exited")
+	if code := body(exited)["exitCode"]; code != float64(0) {
+		t.Errorf("exit code = %v, want 0", code)
+	}
+	c.wait("event", "terminated")
+	if c.output != "9\n" {
+		t.Errorf("output = %q, want %q", c.output, "9\n")
+	}
+
+	c.send("disconnect", nil)
+}
+
+func TestLocalsAfterAssertThrows(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "throws.brz")
+	// 'fail' is left by its error, without returning, when 'assert-throws'
+	// catches it.
+	source := "define fail 1 0 / end\ndefine square\n    dup\n    *\nend\n1 assert-throws fail end\n3 square\n"
+	if e := os.WriteFile(program, []byte(source), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	c := newClient(t)
+	c.send("initialize", map[string]any{"adapterID": "beremiz"})
+	c.send("launch", map[string]any{"program": program})
+	c.send("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": program},
+		"breakpoints": []map[string]any{{"line": 4}},
+	})
+	c.send("configurationDone", nil)
+	c.wait("event", "stopped")
+
+	frames := list(c.send("stackTrace", map[string]any{"threadId": 1}), "stackFrames")
+	if len(frames) != 2 || frames[0]["name"] != "square" {
+		t.Fatalf("stackFrames = %v, want square called from <main>", frames)
+	}
+
+	// The stack is [1 3 3]: square was called on [1 3].
+	locals := list(c.send("variables", map[string]any{"variablesReference": 1000}), "variables")
+	if len(locals) != 1 || locals[0]["name"] != "[2]" || locals[0]["value"] != "3" {
+		t.Errorf("locals = %v, want [2] = 3", locals)
+	}
+
+	c.send("continue", map[string]any{"threadId": 1})
+	c.wait("event", "terminated")
+	c.send("disconnect", nil)
+}
+
+func TestLaunchWithoutProgram(t *testing.T) {
+	c := newClient(t)
+	c.send("initialize", nil)
+
+	c.seq++
+	wire.Write(c.in, map[string]any{"seq": c.seq, "type": "request", "command": "launch", "arguments": map[string]any{}})
+	if resp := c.wait("response", "launch"); resp["success"] == true {
+		t.Errorf("launch without a program succeeded")
+	}
+	c.send("disconnect", nil)
+}
@@ -0,0 +1,258 @@
+package debug
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+// Breakpoint pauses the program at a line, or when a word is called.
+type Breakpoint struct {
+	ID int
+	// File and Line match instructions written on that line. An empty File
+	// matches any file.
+	File string
+	Line int
+	// Word matches the first step of each call of that word: an
+	// instruction, or the call of another word.
+	Word string
+}
+
+func (b Breakpoint) String() string {
+	if b.Word != "" {
+		return fmt.Sprintf("#%d word '%s'", b.ID, b.Word)
+	}
+	if b.File == "" {
+		return fmt.Sprintf("#%d line %d", b.ID, b.Line)
+	}
+	return fmt.Sprintf("#%d %s:%d", b.ID, b.File, b.Line)
+}
+
+// matches reports whether b stops at loc.
+func (b Breakpoint) matches(loc beremiz.Loc) bool {
+	if b.Word != "" || b.Line != loc.Line {
+		return false
+	}
+	return b.File == "" || b.File == loc.File || b.File == filepath.Base(loc.File)
+}
+
+// ParseBreakpoint reads a breakpoint from spec: "file:line", "line" or a
+// word name. Its ID is left unset.
+func ParseBreakpoint(spec string) (Breakpoint, error) {
+	file, line, found := strings.Cut(spec, ":")
+	if !found {
+		file, line = "", spec
+	}
+
+	if n, e := strconv.Atoi(line); e == nil {
+		if n < 1 {
+			return Breakpoint{}, fmt.Errorf("invalid line %d", n)
+		}
+		return Breakpoint{File: file, Line: n}, nil
+	}
+
+	switch {
+	case found:
+		return Breakpoint{}, fmt.Errorf("invalid line '%s'", line)
+	case spec == "":
+		return Breakpoint{}, fmt.Errorf("missing breakpoint location")
+	}
+	return Breakpoint{Word: spec}, nil
+}
+
+// Reason tells why the program paused. The values are the ones the Debug
+// Adapter Protocol uses.
+type Reason string
+
+const (
+	ReasonEntry              Reason = "entry"
+	ReasonStep               Reason = "step"
+	ReasonBreakpoint         Reason = "breakpoint"
+	ReasonFunctionBreakpoint Reason = "function breakpoint"
+	ReasonPause              Reason = "pause"
+)
+
+// mode is what the program does until the next pause.
+type mode int
+
+const (
+	// stepping pauses at the next instruction, call or return.
+	stepping mode = iota
+	// stepOver pauses at the next instruction or call not inside a deeper
+	// call, or when the current word returns.
+	stepOver
+	// stepOut pauses when the current word returns.
+	stepOut
+	// running only pauses at breakpoints.
+	running
+)
+
+// Controller decides where a program pauses: at breakpoints, and where the
+// step commands say. It keeps no I/O of its own, so every debugger front end
+// shares it. It starts out pausing at the first instruction.
+type Controller struct {
+	breakpoints []Breakpoint
+	nextID      int
+
+	mode mode
+	// reason is why stepping mode pauses.
+	reason Reason
+	// depth is the call depth the last step command was given at, as
+	// depth counts it.
+	depth int
+	// last is where the previous event was written, so a line breakpoint
+	// only stops when the program arrives at its line.
+	last beremiz.Loc
+	// entered holds the word whose call just started, when it has a
+	// breakpoint, until the next event pauses for it.
+	entered string
+}
+
+// NewController returns a Controller that pauses at the first instruction.
+func NewController() *Controller {
+	return &Controller{nextID: 1, reason: ReasonEntry}
+}
+
+// Break adds a breakpoint from spec, as read by ParseBreakpoint.
+func (c *Controller) Break(spec string) (Breakpoint, error) {
+	b, e := ParseBreakpoint(spec)
+	if e != nil {
+		return Breakpoint{}, e
+	}
+	return c.Add(b), nil
+}
+
+// Add adds b, giving it the next ID.
+func (c *Controller) Add(b Breakpoint) Breakpoint {
+	b.ID = c.nextID
+	c.nextID++
+	c.breakpoints = append(c.breakpoints, b)
+	return b
+}
+
+// Delete removes the breakpoint with id.
+func (c *Controller) Delete(id int) bool {
+	for i, b := range c.breakpoints {
+		if b.ID == id {
+			c.breakpoints = append(c.breakpoints[:i], c.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ClearLines removes the line breakpoints in file.
+func (c *Controller) ClearLines(file string) {
+	c.remove(func(b Breakpoint) bool { return b.Word == "" && b.File == file })
+}
+
+// ClearWords removes the word breakpoints.
+func (c *Controller) ClearWords() {
+	c.remove(func(b Breakpoint) bool { return b.Word != "" })
+}
+
+func (c *Controller) remove(match func(Breakpoint) bool) {
+	kept := c.breakpoints[:0]
+	for _, b := range c.breakpoints {
+		if !match(b) {
+			kept = append(kept, b)
+		}
+	}
+	c.breakpoints = kept
+}
+
+// Breakpoints returns the breakpoints, in the order they were added.
+func (c *Controller) Breakpoints() []Breakpoint {
+	return c.breakpoints
+}
+
+// Step pauses at the next instruction.
+func (c *Controller) Step() {
+	c.mode, c.reason = stepping, ReasonStep
+}
+
+// Pause pauses at the next instruction, when asked to from outside the
+// program.
+func (c *Controller) Pause() {
+	c.mode, c.reason = stepping, ReasonPause
+}
+
+// Next pauses at the next instruction or call that isn't inside a word
+// called from where ev is.
+func (c *Controller) Next(ev *beremiz.Event) {
+	c.mode, c.depth = stepOver, depth(ev)
+}
+
+// Out pauses when the word running at ev returns. It returns false, and
+// changes nothing, when ev isn't inside a word.
+func (c *Controller) Out(ev *beremiz.Event) bool {
+	if depth(ev) == 0 {
+		return false
+	}
+	c.mode, c.depth = stepOut, depth(ev)
+	return true
+}
+
+// Continue only pauses at breakpoints.
+func (c *Controller) Continue() {
+	c.mode = running
+}
+
+// depth returns how many words are running at ev. A Return is counted at
+// the depth of the caller it returns to, like the call it ends.
+func depth(ev *beremiz.Event) int {
+	if ev.Kind == beremiz.Return {
+		return len(ev.Frames) - 1
+	}
+	return len(ev.Frames)
+}
+
+// Check is called for every event before it runs, and reports whether the
+// program should pause there and why. Step commands pause at calls and
+// returns too, at the place the word is called; breakpoints only pause at
+// instructions.
+func (c *Controller) Check(ev *beremiz.Event) (bool, Reason) {
+	var reason Reason
+	depth := depth(ev)
+
+	switch {
+	case c.mode == stepping:
+		reason = c.reason
+	// Stepping over a call doesn't pause again at its return.
+	case c.mode == stepOver && (depth < c.depth || depth == c.depth && ev.Kind != beremiz.Return),
+		c.mode == stepOut && depth < c.depth:
+		reason = ReasonStep
+	}
+
+	if c.entered != "" {
+		reason = ReasonFunctionBreakpoint
+		c.entered = ""
+	}
+
+	if ev.Kind != beremiz.Instruction {
+		if ev.Kind == beremiz.Call {
+			for _, b := range c.breakpoints {
+				if b.Word == ev.Word {
+					c.entered = ev.Word
+				}
+			}
+		}
+		// Each call arrives at the lines of the word anew.
+		c.last = ev.Loc
+		return reason != "", reason
+	}
+
+	if ev.Loc.Line != c.last.Line || ev.Loc.File != c.last.File {
+		for _, b := range c.breakpoints {
+			if b.matches(ev.Loc) {
+				reason = ReasonBreakpoint
+			}
+		}
+	}
+	c.last = ev.Loc
+
+	return reason != "", reason
+}
@@ -0,0 +1,300 @@
+// Package debug is an interactive, line-oriented debugger for Beremiz
+// programs. It watches a program through a beremiz.Hook and pauses it at
+// breakpoints or after each step, reading commands until told to go on.
+package debug
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+// ErrQuit is returned by Eval when the user quits the debugger.
+var ErrQuit = errors.New("debugger quit")
+
+// Debugger is a beremiz.Hook that pauses the program and asks the user what
+// to do. It starts paused at the first instruction.
+type Debugger struct {
+	*Controller
+
+	in  *bufio.Reader
+	out io.Writer
+
+	sources map[string][]string
+	// command is the last command, repeated by an empty line.
+	command string
+}
+
+// New returns a Debugger reading commands from in and writing to out.
+func New(in *bufio.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		Controller: NewController(),
+		in:         in,
+		out:        out,
+		sources:    map[string][]string{},
+	}
+}
+
+// AddSource makes the source of file available to the list command.
+func (d *Debugger) AddSource(file, source string) {
+	d.sources[file] = strings.Split(source, "\n")
+}
+
+func (d *Debugger) Before(ev *beremiz.Event) error {
+	if pause, _ := d.Check(ev); !pause {
+		return nil
+	}
+	return d.pause(ev)
+}
+
+func (d *Debugger) After(ev *beremiz.Event) error {
+	return nil
+}
+
+// pause shows where the program stopped and runs commands until one of them
+// resumes it.
+func (d *Debugger) pause(ev *beremiz.Event) error {
+	ev.Flush()
+	d.where(ev)
+
+	for {
+		fmt.Fprint(d.out, "(brzdb) ")
+		line, e := d.in.ReadString('\n')
+		if e != nil && line == "" {
+			fmt.Fprintln(d.out)
+			return ErrQuit
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = d.command
+		}
+		d.command = line
+
+		resume, e := d.run(ev, line)
+		if e != nil {
+			return e
+		}
+		if resume {
+			return nil
+		}
+	}
+}
+
+// run runs one command. It returns true when the program should go on.
+func (d *Debugger) run(ev *beremiz.Event, line string) (bool, error) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "":
+		return false, nil
+
+	case "s", "step":
+		d.Step()
+		return true, nil
+
+	case "n", "next":
+		d.Next(ev)
+		return true, nil
+
+	case "o", "out", "finish":
+		if !d.Out(ev) {
+			fmt.Fprintln(d.out, "Not inside a word; use 'continue' instead.")
+			return false, nil
+		}
+		return true, nil
+
+	case "c", "continue":
+		d.Continue()
+		return true, nil
+
+	case "b", "break":
+		if arg == "" {
+			d.listBreakpoints()
+			return false, nil
+		}
+		b, e := d.Break(arg)
+		if e != nil {
+			fmt.Fprintf(d.out, "Unable to add breakpoint: %v.\n", e)
+			return false, nil
+		}
+		fmt.Fprintf(d.out, "Breakpoint %s\n", b)
+
+	case "d", "delete":
+		id, e := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+		if e != nil || !d.Delete(id) {
+			fmt.Fprintf(d.out, "No breakpoint '%s'.\n", arg)
+		}
+
+	case "st", "stack":
+		printStack(d.out, ev.Stack())
+
+	case "bt", "backtrace", "frames":
+		d.backtrace(ev)
+
+	case "p", "print", "eval":
+		if arg == "" {
+			fmt.Fprintln(d.out, "Usage: print <code>")
+			return false, nil
+		}
+		stack, e := ev.Eval(context.Background(), arg)
+		ev.Flush()
+		if e != nil {
+			fmt.Fprintf(d.out, "Error: %v\n", e)
+			return false, nil
+		}
+		printStack(d.out, stack)
+
+	case "w", "where":
+		d.where(ev)
+
+	case "l", "list":
+		d.list(ev.Loc, 5)
+
+	case "h", "help":
+		fmt.Fprint(d.out, help)
+
+	case "q", "quit":
+		return false, ErrQuit
+
+	default:
+		fmt.Fprintf(d.out, "Unknown command '%s'. Type 'help' for a list.\n", name)
+	}
+
+	return false, nil
+}
+
+const help = `Commands:
+  s, step            run the next instruction, stepping into words
+  n, next            run the next instruction, stepping over words
+  o, out             run until the current word returns
+  c, continue        run until a breakpoint
+  b, break [spec]    add a breakpoint at file:line, line or word, or list them
+  d, delete <id>     remove a breakpoint
+  st, stack          print the data stack
+  bt, backtrace      print the active word calls
+  p, print <code>    run code against a copy of the stack and print the result
+  w, where           show the current instruction
+  l, list            show the source around the current instruction
+  h, help            show this help
+  q, quit            stop the program
+An empty line repeats the last command.
+`
+
+// where prints the current instruction and its line of source. Calls and
+// returns are marked like in a trace.
+func (d *Debugger) where(ev *beremiz.Event) {
+	word := ev.Word
+	switch ev.Kind {
+	case beremiz.Call:
+		word = "> " + word
+	case beremiz.Return:
+		word = "< " + word
+	}
+	fmt.Fprintf(d.out, "-> %s:%d:%d  %s\n", ev.Loc.File, ev.Loc.Line, ev.Loc.Col, word)
+	if line, ok := d.line(ev.Loc.File, ev.Loc.Line); ok {
+		fmt.Fprintf(d.out, "   %d | %s\n", ev.Loc.Line, line)
+	}
+}
+
+func (d *Debugger) line(file string, n int) (string, bool) {
+	lines, ok := d.sources[file]
+	if !ok || n < 1 || n > len(lines) {
+		return "", false
+	}
+	return strings.TrimSuffix(lines[n-1], "\r"), true
+}
+
+// list prints the lines of source within context lines of loc.
+func (d *Debugger) list(loc beremiz.Loc, context int) {
+	lines, ok := d.sources[loc.File]
+	if !ok {
+		fmt.Fprintf(d.out, "No source for '%s'.\n", loc.File)
+		return
+	}
+
+	for n := max(1, loc.Line-context); n <= min(len(lines), loc.Line+context); n++ {
+		marker := "  "
+		if n == loc.Line {
+			marker = "->"
+		}
+		line, _ := d.line(loc.File, n)
+		fmt.Fprintf(d.out, "%s %4d | %s\n", marker, n, line)
+	}
+}
+
+func (d *Debugger) listBreakpoints() {
+	if len(d.Breakpoints()) == 0 {
+		fmt.Fprintln(d.out, "No breakpoints.")
+		return
+	}
+	for _, b := range d.Breakpoints() {
+		fmt.Fprintln(d.out, b)
+	}
+}
+
+// backtrace prints the active word calls, innermost first, like the frames
+// of a traceback read from the bottom.
+func (d *Debugger) backtrace(ev *beremiz.Event) {
+	in := "<main>"
+	if len(ev.Frames) > 0 {
+		in = ev.Frames[len(ev.Frames)-1].Name
+	}
+	fmt.Fprintf(d.out, "#0 %s at %s:%d:%d\n", in, ev.Loc.File, ev.Loc.Line, ev.Loc.Col)
+
+	for i := len(ev.Frames) - 1; i >= 0; i-- {
+		in := "<main>"
+		if i > 0 {
+			in = ev.Frames[i-1].Name
+		}
+		loc := ev.Frames[i].Loc
+		fmt.Fprintf(d.out, "#%d %s at %s:%d:%d\n", len(ev.Frames)-i, in, loc.File, loc.Line, loc.Col)
+	}
+}
+
+// printStack prints stack in the same layout as the dump word.
+func printStack(w io.Writer, stack []any) {
+	fmt.Fprintf(w, "Stack[%d]:\n", len(stack))
+	for i, v := range stack {
+		fmt.Fprintf(w, "  %d: (%s) %s", i, TypeName(v), FormatValue(v))
+		if i == len(stack)-1 {
+			fmt.Fprint(w, "  <- top")
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// TypeName returns the Beremiz type of a stack value, as dump names it.
+func TypeName(v any) string {
+	switch v.(type) {
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	default:
+		return "nil"
+	}
+}
+
+// FormatValue returns a stack value the way write prints it.
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,147 @@
+package debug_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/debug"
+)
+
+const source = `define square
+    dup *
+end
+define twice
+    square square
+end
+2 twice
+writeln
+`
+
+// session runs source under a Debugger reading the commands of script, one
+// per line, and returns where it paused and what it printed.
+func session(t *testing.T, script string, breakpoints ...string) ([]string, string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	d := debug.New(bufio.NewReader(strings.NewReader(script)), &out)
+	d.AddSource("t.brz", source)
+	for _, spec := range breakpoints {
+		if _, e := d.Break(spec); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	var stdout bytes.Buffer
+	_, e := beremiz.New(beremiz.WithHook(d), beremiz.WithStdout(&stdout)).EvalSource(context.Background(), "t.brz", source)
+
+	var pauses []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if _, where, ok := strings.Cut(line, "-> "); ok {
+			pauses = append(pauses, where)
+		}
+	}
+	return pauses, out.String(), e
+}
+
+func TestStepping(t *testing.T) {
+	cases := []struct {
+		name        string
+		script      string
+		breakpoints []string
+		want        []string
+	}{
+		{"step", "s\ns\ns\ns\ns\ns\ns\nc\n", nil, []string{
+			"t.brz:7:1  2",
+			"t.brz:7:3  > twice",
+			"t.brz:5:5  > square",
+			"t.brz:2:5  dup",
+			"t.brz:2:9  *",
+			"t.brz:5:5  < square",
+			"t.brz:5:12  > square",
+			"t.brz:2:5  dup",
+		}},
+		{"next", "n\nn\nn\n", nil, []string{
+			"t.brz:7:1  2",
+			"t.brz:7:3  > twice",
+			"t.brz:8:1  writeln",
+		}},
+		{"out", "s\ns\ns\no\nn\nn\nn\nc\n", nil, []string{
+			"t.brz:7:1  2",
+			"t.brz:7:3  > twice",
+			"t.brz:5:5  > square",
+			"t.brz:2:5  dup",
+			"t.brz:5:5  < square",
+			"t.brz:5:12  > square",
+			"t.brz:7:3  < twice",
+			"t.brz:8:1  writeln",
+		}},
+		{"next from the end of a word", "s\ns\ns\ns\nn\nn\nc\n", nil, []string{
+			"t.brz:7:1  2",
+			"t.brz:7:3  > twice",
+			"t.brz:5:5  > square",
+			"t.brz:2:5  dup",
+			"t.brz:2:9  *",
+			"t.brz:5:5  < square",
+			"t.brz:5:12  > square",
+		}},
+		{"continue", "c\n", nil, []string{"t.brz:7:1  2"}},
+		// Each call arrives at line 2 again.
+		{"line breakpoint", "c\nc\nc\n", []string{"2"}, []string{
+			"t.brz:7:1  2",
+			"t.brz:2:5  dup",
+			"t.brz:2:5  dup",
+		}},
+		{"file and line breakpoint", "c\nc\n", []string{"t.brz:8"}, []string{
+			"t.brz:7:1  2",
+			"t.brz:8:1  writeln",
+		}},
+		{"word breakpoint", "c\nc\nc\nc\n", []string{"square", "twice"}, []string{
+			"t.brz:7:1  2",
+			"t.brz:5:5  > square",
+			"t.brz:2:5  dup",
+			"t.brz:2:5  dup",
+		}},
+		{"breakpoint added while paused", "b 8\nc\nc\n", nil, []string{
+			"t.brz:7:1  2",
+			"t.brz:8:1  writeln",
+		}},
+		{"deleted breakpoint", "d 1\nc\n", []string{"2"}, []string{"t.brz:7:1  2"}},
+		{"step out of main", "o\nc\n", nil, []string{"t.brz:7:1  2"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pauses, out, e := session(t, c.script, c.breakpoints...)
+			if e != nil {
+				t.Fatalf("EvalSource: %v\n%s", e, out)
+			}
+			if !reflect.DeepEqual(pauses, c.want) {
+				t.Errorf("paused at\n%q\nwant\n%q\n%s", pauses, c.want, out)
+			}
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	_, out, e := session(t, "c\nst\nbt\np 1 +\nb\nq\n", "square")
+	if !errors.Is(e, debug.ErrQuit) {
+		t.Errorf("EvalSource: %v, want ErrQuit", e)
+	}
+
+	for _, want := range []string{
+		"-> t.brz:2:5  dup\n   2 |     dup *\n",
+		"Stack[1]:\n  0: (int) 2  <- top\n",
+		"#0 square at t.brz:2:5\n#1 twice at t.brz:5:5\n#2 <main> at t.brz:7:3\n",
+		"Stack[1]:\n  0: (int) 3  <- top\n",
+		"#1 word 'square'\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't hold %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package dump writes what the lexer and the parser make of a program, for
+// people writing tools: the tokens, with the jumps resolved, and the tree
+// of blocks. Both come as aligned text or as JSON.
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Format is how a dump is written.
+type Format string
+
+const (
+	FormatHuman Format = "human"
+	FormatJSON  Format = "json"
+)
+
+// ParseFormat returns the Format named name.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatHuman, FormatJSON:
+		return Format(name), nil
+	}
+	return "", fmt.Errorf("unknown format '%s' (expected human or json)", name)
+}
+
+// loc is the JSON form of a tokens.Loc.
+type loc struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+}
+
+func locOf(l tokens.Loc) loc {
+	return loc{File: l.File, Line: l.Line, Col: l.Col}
+}
+
+// token is the JSON form of a tokens.Token. JmpTo is left out when the
+// token doesn't jump.
+type token struct {
+	Index   int              `json:"index"`
+	Type    tokens.TokenType `json:"type"`
+	Literal any              `json:"literal"`
+	Loc     loc              `json:"loc"`
+	Len     int              `json:"len"`
+	JmpTo   int              `json:"jmpTo,omitempty"`
+}
+
+// index is where each token is in the program, so jumps can be followed
+// from the tree.
+type index map[tokens.Loc]int
+
+func tokenOf(t tokens.Token, i int) token {
+	return token{Index: i, Type: t.Type, Literal: t.Literal, Loc: locOf(t.Loc), Len: t.Len, JmpTo: t.JmpTo}
+}
+
+// literal writes the literal of t as it would be written in source.
+func literal(t tokens.Token) string {
+	switch v := t.Literal.(type) {
+	case string:
+		if t.Type == tokens.String {
+			return strconv.Quote(v)
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(t.Literal)
+}
+
+func encode(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Tokens writes ts, one per line with its index, location, type, literal,
+// length and jump target.
+func Tokens(w io.Writer, ts []tokens.Token, format Format) error {
+	if format == FormatJSON {
+		list := make([]token, len(ts))
+		for i, t := range ts {
+			list[i] = tokenOf(t, i)
+		}
+		return encode(w, list)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tLOC\tTYPE\tLITERAL\tLEN\tJUMP")
+	for i, t := range ts {
+		jump := ""
+		if t.JmpTo > 0 {
+			jump = fmt.Sprintf("-> %d", t.JmpTo)
+		}
+		fmt.Fprintf(tw, "%d\t%d:%d\t%s\t%s\t%d\t%s\n", i, t.Loc.Line, t.Loc.Col, t.Type, literal(t), t.Len, jump)
+	}
+	return tw.Flush()
+}
+
+// node is the JSON form of a parser.Node.
+type node struct {
+	Kind     parser.NodeKind `json:"kind"`
+	Token    *token          `json:"token,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Cond     []node          `json:"cond,omitempty"`
+	Body     []node          `json:"body,omitempty"`
+	Branches []node          `json:"branches,omitempty"`
+	Do       *token          `json:"do,omitempty"`
+	End      *token          `json:"end,omitempty"`
+}
+
+// Tree writes the block tree of a program made of ts.
+func Tree(w io.Writer, root *parser.Node, ts []tokens.Token, format Format) error {
+	idx := index{}
+	for i, t := range ts {
+		idx[t.Loc] = i
+	}
+
+	if format == FormatJSON {
+		return encode(w, idx.node(root))
+	}
+
+	var b strings.Builder
+	idx.write(&b, root, 0)
+	_, e := io.WriteString(w, b.String())
+	return e
+}
+
+func (idx index) token(t *tokens.Token) *token {
+	if t == nil {
+		return nil
+	}
+	jt := tokenOf(*t, idx[t.Loc])
+	return &jt
+}
+
+func (idx index) nodes(ns []*parser.Node) []node {
+	var list []node
+	for _, n := range ns {
+		list = append(list, idx.node(n))
+	}
+	return list
+}
+
+func (idx index) node(n *parser.Node) node {
+	jn := node{
+		Kind:     n.Kind,
+		Name:     n.Name,
+		Cond:     idx.nodes(n.Cond),
+		Body:     idx.nodes(n.Body),
+		Branches: idx.nodes(n.Branches),
+		Do:       idx.token(n.Do),
+		End:      idx.token(n.End),
+	}
+	jn.Token = idx.token(n.Token)
+	return jn
+}
+
+// write writes n and what it holds, indented by depth.
+func (idx index) write(b *strings.Builder, n *parser.Node, depth int) {
+	pad := strings.Repeat("  ", depth)
+
+	switch n.Kind {
+	case parser.NodeProgram:
+		b.WriteString("program\n")
+		for _, child := range n.Body {
+			idx.write(b, child, depth+1)
+		}
+		return
+	case parser.NodeInstruction:
+		fmt.Fprintf(b, "%s%s %s %s\n", pad, n.Token.Type, literal(*n.Token), idx.at(*n.Token))
+		return
+	case parser.NodeBranch:
+		fmt.Fprintf(b, "%sbranch %s %s\n", pad, literal(*n.Token), idx.at(*n.Token))
+	default:
+		fmt.Fprintf(b, "%s%s", pad, n.Kind)
+		if n.Name != "" {
+			fmt.Fprintf(b, " %s", n.Name)
+		}
+		fmt.Fprintf(b, " %s\n", idx.at(*n.Token))
+	}
+
+	inner := strings.Repeat("  ", depth+1)
+	if n.Kind == parser.NodeFor || n.Kind == parser.NodeBranch && n.Token.Type != tokens.Else {
+		idx.section(b, "cond", n.Cond, depth+1)
+		if n.Do != nil {
+			fmt.Fprintf(b, "%sdo %s\n", inner, idx.at(*n.Do))
+		}
+	}
+	for _, branch := range n.Branches {
+		idx.write(b, branch, depth+1)
+	}
+	if n.Kind != parser.NodeIf {
+		idx.section(b, "body", n.Body, depth+1)
+	}
+	if n.End != nil {
+		fmt.Fprintf(b, "%send %s\n", inner, idx.at(*n.End))
+	}
+}
+
+// at writes where t is, as line:col and index, and where it jumps to.
+func (idx index) at(t tokens.Token) string {
+	s := fmt.Sprintf("@%d:%d #%d", t.Loc.Line, t.Loc.Col, idx[t.Loc])
+	if t.JmpTo > 0 {
+		s += fmt.Sprintf(" -> #%d", t.JmpTo)
+	}
+	return s
+}
+
+// section writes a labelled list of nodes.
+func (idx index) section(b *strings.Builder, label string, ns []*parser.Node, depth int) {
+	fmt.Fprintf(b, "%s%s\n", strings.Repeat("  ", depth), label)
+	for _, n := range ns {
+		idx.write(b, n, depth+1)
+	}
+}
@@ -0,0 +1,79 @@
+package dump_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/dump"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestGolden dumps testdata/program.brz in every format and compares the
+// output with the golden files next to it. Run with -update to rewrite
+// them.
+func TestGolden(t *testing.T) {
+	source, e := os.ReadFile(filepath.Join("testdata", "program.brz"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	ts, e := lexer.New(string(source), "program.brz").Tokenize()
+	if e != nil {
+		t.Fatalf("Tokenize: %v", e)
+	}
+	p := parser.New(ts, false)
+	if e := p.Check(); e != nil {
+		t.Fatalf("Check: %v", e)
+	}
+
+	cases := []struct {
+		golden string
+		dump   func(*bytes.Buffer) error
+	}{
+		{"program.tokens", func(b *bytes.Buffer) error { return dump.Tokens(b, ts, dump.FormatHuman) }},
+		{"program.tokens.json", func(b *bytes.Buffer) error { return dump.Tokens(b, ts, dump.FormatJSON) }},
+		{"program.ast", func(b *bytes.Buffer) error { return dump.Tree(b, p.Tree(), ts, dump.FormatHuman) }},
+		{"program.ast.json", func(b *bytes.Buffer) error { return dump.Tree(b, p.Tree(), ts, dump.FormatJSON) }},
+	}
+
+	for _, c := range cases {
+		t.Run(c.golden, func(t *testing.T) {
+			var got bytes.Buffer
+			if e := c.dump(&got); e != nil {
+				t.Fatal(e)
+			}
+
+			path := filepath.Join("testdata", c.golden)
+			if *update {
+				if e := os.WriteFile(path, got.Bytes(), 0o644); e != nil {
+					t.Fatal(e)
+				}
+				return
+			}
+
+			want, e := os.ReadFile(path)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s differs (run go test -update to accept the new output):\n%s", path, got.String())
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"human", "json"} {
+		if f, e := dump.ParseFormat(name); e != nil || string(f) != name {
+			t.Errorf("ParseFormat(%q) = %q, %v", name, f, e)
+		}
+	}
+	if _, e := dump.ParseFormat("xml"); e == nil {
+		t.Error("ParseFormat(\"xml\") succeeded")
+	}
+}
@@ -1,59 +1,231 @@
 package err
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
 )
 
-func red(content string) string {
-	return fmt.Sprintf("\x1b[31m%s\x1b[0m", content)
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return "error"
+	}
 }
 
-func Error(message string) {
-	fmt.Fprintf(os.Stderr, "%s%s\n", red("Error: "), message)
+// Code identifies a kind of diagnostic. E1xx codes come from the lexer, E2xx
+// from checking blocks and names before the program runs, E3xx from running
+// it and E4xx from failed assertions. L1xx codes are the warnings of the linter.
+type Code string
+
+const (
+	InvalidCharacter   Code = "E101"
+	InvalidNumber      Code = "E102"
+	UnterminatedString Code = "E103"
+	InvalidEscape      Code = "E104"
+
+	InvalidBlock   Code = "E201"
+	MissingName    Code = "E202"
+	UnclosedBlock  Code = "E203"
+	UnknownName    Code = "E204"
+	NotImplemented Code = "E299"
+
+	StackUnderflow   Code = "E301"
+	TypeMismatch     Code = "E302"
+	UndefinedName    Code = "E303"
+	ArithmeticError  Code = "E304"
+	IOError          Code = "E305"
+	PermissionDenied Code = "E306"
+	LimitExceeded    Code = "E307"
+	HostError        Code = "E308"
+	OutOfRange       Code = "E309"
+
+	AssertionFailed Code = "E401"
+)
+
+// Kind returns the name shown in front of the message, such as
+// "LexerError".
+func (c Code) Kind() string {
+	if len(c) < 2 {
+		return "Error"
+	}
+	if c[0] == 'L' {
+		return "Lint"
+	}
+
+	switch c[1] {
+	case '1':
+		return "LexerError"
+	case '2':
+		return "SyntaxError"
+	case '3':
+		return "RuntimeError"
+	case '4':
+		return "AssertionError"
+	}
+	return "Error"
+}
+
+// Span is a range of source code. Lines and columns start at 1 and columns
+// count runes; the end is exclusive.
+type Span struct {
+	File      string
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+}
+
+// Start returns the location where the span begins.
+func (s Span) Start() tokens.Loc {
+	return tokens.Loc{File: s.File, Line: s.StartLine, Col: s.StartCol}
+}
+
+// SpanAt returns the span of length runes starting at loc.
+func SpanAt(loc tokens.Loc, length int) Span {
+	return Span{
+		File:      loc.File,
+		StartLine: loc.Line,
+		StartCol:  loc.Col,
+		EndLine:   loc.Line,
+		EndCol:    loc.Col + max(length, 1),
+	}
 }
 
-func LexerError(lines []string, loc tokens.Loc, message string, tailLength int) {
-	fmt.Fprintf(os.Stderr, "%s%s\n", red("LexerError: "), message)
+// TokenSpan returns the span of token in the source. Tokens made while
+// running the program have no source length, so their printed literal is
+// used instead.
+func TokenSpan(token tokens.Token) Span {
+	length := token.Len
+	if length == 0 {
+		lit, ok := token.Literal.(string)
+		if !ok {
+			lit = fmt.Sprint(token.Literal)
+		}
+		length = utf8.RuneCountInString(lit)
+	}
+	return SpanAt(token.Loc, length)
+}
 
-	line := lines[loc.Line-1]
-	prefix := fmt.Sprintf("%s:%d:%d: ", loc.File, loc.Line, loc.Col)
+// Frame is an active call of a defined word.
+type Frame struct {
+	Name string
+	// Loc is where the word was called.
+	Loc tokens.Loc
+}
 
-	fmt.Printf("\x1b[31m%s\x1b[0m%s\n", prefix, line)
+// Diagnostic is a problem found in a program. It is also the error value
+// returned by the lexer and the parser.
+type Diagnostic struct {
+	Severity    Severity
+	Code        Code
+	Message     string
+	Span        Span
+	Notes       []string
+	Suggestions []string
+	// Trace lists the word calls active when a runtime error happened,
+	// outermost first.
+	Trace []Frame
+	// Err is the underlying error, if any, such as one returned by a host
+	// word.
+	Err error
+}
 
-	trail := strings.Repeat(" ", loc.Col-1+len(prefix))
-	fmt.Print(trail)
-	fmt.Print(red("^"))
-	if tailLength > 0 {
-		fmt.Print(red(strings.Repeat("~", tailLength)))
-	} else if tailLength == -1 {
-		length := len(line) - loc.Col - 1
-		fmt.Print(red(strings.Repeat("~", length)))
+// New returns an error diagnostic.
+func New(code Code, span Span, message string) *Diagnostic {
+	return &Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Message:  message,
+		Span:     span,
 	}
-	fmt.Print("\n")
 }
 
-func SyntaxError(token tokens.Token, message string, lines []string) {
-	fmt.Fprintf(os.Stderr, "%s%s\n\n", red("SyntaxError: "), message)
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.Span.File, d.Span.StartLine, d.Span.StartCol, d.Message)
+}
 
-	line := lines[token.Loc.Line-1]
-	fmt.Println(token.Loc.File + ":\n")
-	prefix := fmt.Sprintf("%d:%d | ", token.Loc.Line, token.Loc.Col)
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
 
-	fmt.Printf("\x1b[31m%s\x1b[0m%s\n", prefix, line)
+// Note adds a note explaining the diagnostic and returns d.
+func (d *Diagnostic) Note(format string, args ...any) *Diagnostic {
+	d.Notes = append(d.Notes, fmt.Sprintf(format, args...))
+	return d
+}
+
+// Suggest adds a suggested fix and returns d.
+func (d *Diagnostic) Suggest(format string, args ...any) *Diagnostic {
+	d.Suggestions = append(d.Suggestions, fmt.Sprintf(format, args...))
+	return d
+}
 
-	trail := strings.Repeat(" ", token.Loc.Col-1+len(prefix)-1)
-	fmt.Print(trail)
+// List is a group of diagnostics reported together.
+type List []*Diagnostic
 
-	lit, ok := token.Literal.(string)
-	if !ok {
-		lit = fmt.Sprint(token.Literal)
+func (l List) Error() string {
+	messages := make([]string, len(l))
+	for i, d := range l {
+		messages[i] = d.Error()
 	}
-	fmt.Print(red("^"))
-	fmt.Print(red(strings.Repeat("~", len(lit)-1)))
+	return strings.Join(messages, "\n")
+}
 
-	fmt.Print("\n")
+func (l List) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, d := range l {
+		errs[i] = d
+	}
+	return errs
+}
+
+// Err returns l as an error, or nil when it is empty.
+func (l List) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Sort orders l by where each diagnostic starts in the source.
+func (l List) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Span, l[j].Span
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.StartCol < b.StartCol
+	})
+}
+
+// Diagnostics returns the diagnostics carried by e: every one in a List, or
+// the single Diagnostic that e is or wraps. It returns nil for other errors.
+func Diagnostics(e error) List {
+	var l List
+	if errors.As(e, &l) {
+		return l
+	}
+
+	var d *Diagnostic
+	if errors.As(e, &d) {
+		return List{d}
+	}
+	return nil
 }
@@ -0,0 +1,122 @@
+package err
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is a way of printing diagnostics.
+type Format string
+
+const (
+	// FormatHuman quotes the source with a caret under the problem.
+	FormatHuman Format = "human"
+	// FormatJSON prints one JSON object per line, for tools.
+	FormatJSON Format = "json"
+	// FormatGCC prints file:line:col: severity: message, one per line, like
+	// gcc does.
+	FormatGCC Format = "gcc"
+)
+
+// Formats lists every Format, in the order shown in help text.
+var Formats = []Format{FormatHuman, FormatJSON, FormatGCC}
+
+// ParseFormat returns the Format named name.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown error format '%s' (expected %s)", name, strings.Join(names, ", "))
+}
+
+// jsonDiagnostic is the JSON form of a Diagnostic. Location fields are left
+// out for problems with no place in the source.
+type jsonDiagnostic struct {
+	Severity    string      `json:"severity"`
+	Code        Code        `json:"code,omitempty"`
+	Kind        string      `json:"kind,omitempty"`
+	Message     string      `json:"message"`
+	File        string      `json:"file,omitempty"`
+	Line        int         `json:"line,omitempty"`
+	Column      int         `json:"column,omitempty"`
+	EndLine     int         `json:"endLine,omitempty"`
+	EndColumn   int         `json:"endColumn,omitempty"`
+	Notes       []string    `json:"notes,omitempty"`
+	Suggestions []string    `json:"suggestions,omitempty"`
+	Trace       []jsonFrame `json:"trace,omitempty"`
+}
+
+// jsonFrame is the JSON form of a Frame.
+type jsonFrame struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func writeJSON(w io.Writer, d jsonDiagnostic) {
+	line, e := json.Marshal(d)
+	if e != nil {
+		// Only strings and ints are marshalled, so this can't happen.
+		panic(e)
+	}
+	fmt.Fprintf(w, "%s\n", line)
+}
+
+func (r *Renderer) renderJSON(d *Diagnostic) {
+	var trace []jsonFrame
+	for _, frame := range d.Trace {
+		trace = append(trace, jsonFrame{
+			Name:   frame.Name,
+			File:   frame.Loc.File,
+			Line:   frame.Loc.Line,
+			Column: frame.Loc.Col,
+		})
+	}
+
+	writeJSON(r.w, jsonDiagnostic{
+		Severity:    d.Severity.String(),
+		Code:        d.Code,
+		Kind:        d.Code.Kind(),
+		Message:     d.Message,
+		File:        d.Span.File,
+		Line:        d.Span.StartLine,
+		Column:      d.Span.StartCol,
+		EndLine:     d.Span.EndLine,
+		EndColumn:   d.Span.EndCol,
+		Notes:       d.Notes,
+		Suggestions: d.Suggestions,
+		Trace:       trace,
+	})
+}
+
+func (r *Renderer) renderGCC(d *Diagnostic) {
+	loc := fmt.Sprintf("%s:%d:%d", d.Span.File, d.Span.StartLine, d.Span.StartCol)
+
+	message := d.Message
+	if d.Code != "" {
+		message += " [" + string(d.Code) + "]"
+	}
+	fmt.Fprintf(r.w, "%s: %s: %s\n", loc, d.Severity, message)
+
+	for _, note := range d.Notes {
+		fmt.Fprintf(r.w, "%s: note: %s\n", loc, note)
+	}
+	for _, suggestion := range d.Suggestions {
+		fmt.Fprintf(r.w, "%s: note: help: %s\n", loc, suggestion)
+	}
+	for i := len(d.Trace) - 1; i >= 0; i-- {
+		frame := d.Trace[i]
+		fmt.Fprintf(r.w, "%s:%d:%d: note: called '%s' from here\n",
+			frame.Loc.File, frame.Loc.Line, frame.Loc.Col, frame.Name)
+	}
+}
@@ -0,0 +1,106 @@
+package err_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+)
+
+func TestRenderJSON(t *testing.T) {
+	d := typeMismatch()
+	d.Note("a note").Suggest("a fix")
+
+	var out bytes.Buffer
+	r := err.NewRenderer(&out, err.FormatJSON, true)
+	r.Render(d, source)
+	r.Message(err.SeverityError, "Unable to read t.brz.")
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want one per diagnostic:\n%s", len(lines), out.String())
+	}
+
+	var got []map[string]any
+	for _, line := range lines {
+		var v map[string]any
+		if e := json.Unmarshal([]byte(line), &v); e != nil {
+			t.Fatalf("%q: %v", line, e)
+		}
+		got = append(got, v)
+	}
+
+	want := []map[string]any{
+		{
+			"severity": "error", "code": "E302", "kind": "RuntimeError",
+			"message": "Cannot add a string and an int.",
+			"file":    "t.brz", "line": 2.0, "column": 11.0, "endLine": 2.0, "endColumn": 12.0,
+			"notes": []any{"a note"}, "suggestions": []any{"a fix"},
+			"trace": []any{map[string]any{"name": "f", "file": "t.brz", "line": 4.0, "column": 1.0}},
+		},
+		// A message has no place in the source.
+		{"severity": "error", "message": "Unable to read t.brz."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON =\n%v\nwant\n%v", got, want)
+	}
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("JSON holds color escapes: %q", out.String())
+	}
+}
+
+func TestRenderGCC(t *testing.T) {
+	d := typeMismatch()
+	d.Note("a note").Suggest("a fix")
+
+	var out bytes.Buffer
+	r := err.NewRenderer(&out, err.FormatGCC, true)
+	r.Render(d, source)
+	r.Message(err.SeverityWarning, "No tests found.")
+
+	want := `t.brz:2:11: error: Cannot add a string and an int. [E302]
+t.brz:2:11: note: a note
+t.brz:2:11: note: help: a fix
+t.brz:4:1: note: called 'f' from here
+beremiz: warning: No tests found.
+`
+	if out.String() != want {
+		t.Errorf("GCC =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range err.Formats {
+		if got, e := err.ParseFormat(string(f)); e != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %q, %v", f, got, e)
+		}
+	}
+	if _, e := err.ParseFormat("xml"); e == nil || !strings.Contains(e.Error(), "expected human, json, gcc") {
+		t.Errorf("ParseFormat(\"xml\") = %v, want an error listing the formats", e)
+	}
+}
+
+func TestTerminalRendererColor(t *testing.T) {
+	f, e := os.Create(filepath.Join(t.TempDir(), "out"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer f.Close()
+
+	// A file isn't a terminal, and NO_COLOR turns colors off anywhere.
+	t.Setenv("NO_COLOR", "1")
+	err.NewTerminalRenderer(f, err.FormatHuman).Render(typeMismatch(), source)
+
+	content, e := os.ReadFile(f.Name())
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(content) == 0 || bytes.Contains(content, []byte("\x1b[")) {
+		t.Errorf("output = %q, want it without color escapes", content)
+	}
+}
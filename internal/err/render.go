@@ -0,0 +1,237 @@
+package err
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Renderer prints diagnostics, for people or for tools. It is the only place
+// that decides how they look.
+type Renderer struct {
+	w      io.Writer
+	format Format
+	color  bool
+}
+
+// NewRenderer returns a Renderer writing to w in format. Colors are only used
+// by FormatHuman, and only when color is set.
+func NewRenderer(w io.Writer, format Format, color bool) *Renderer {
+	return &Renderer{w: w, format: format, color: color}
+}
+
+// NewTerminalRenderer returns a Renderer writing to f in format, with colors
+// when f is a terminal and the NO_COLOR environment variable is not set.
+func NewTerminalRenderer(f *os.File, format Format) *Renderer {
+	return NewRenderer(f, format, IsTerminal(f) && os.Getenv("NO_COLOR") == "")
+}
+
+// IsTerminal reports whether f is connected to a terminal.
+func IsTerminal(f *os.File) bool {
+	info, e := f.Stat()
+	if e != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *Renderer) red(content string) string {
+	if !r.color || content == "" {
+		return content
+	}
+	return fmt.Sprintf("\x1b[31m%s\x1b[0m", content)
+}
+
+func (r *Renderer) cyan(content string) string {
+	if !r.color || content == "" {
+		return content
+	}
+	return fmt.Sprintf("\x1b[36m%s\x1b[0m", content)
+}
+
+// Message prints a problem that isn't tied to a place in the source.
+func (r *Renderer) Message(severity Severity, message string) {
+	switch r.format {
+	case FormatJSON:
+		writeJSON(r.w, jsonDiagnostic{Severity: severity.String(), Message: message})
+		return
+	case FormatGCC:
+		fmt.Fprintf(r.w, "beremiz: %s: %s\n", severity, message)
+		return
+	}
+
+	label := "Error: "
+	if severity != SeverityError {
+		label = strings.ToUpper(severity.String()[:1]) + severity.String()[1:] + ": "
+	}
+	fmt.Fprintf(r.w, "%s%s\n", r.red(label), message)
+}
+
+// Render prints d. The human format quotes the line of source it points at.
+func (r *Renderer) Render(d *Diagnostic, source string) {
+	switch r.format {
+	case FormatJSON:
+		r.renderJSON(d)
+		return
+	case FormatGCC:
+		r.renderGCC(d)
+		return
+	}
+
+	label := d.Code.Kind()
+	if d.Severity != SeverityError {
+		label = d.Severity.String()
+	}
+	if d.Code != "" {
+		label += "[" + string(d.Code) + "]"
+	}
+	fmt.Fprintf(r.w, "%s%s\n\n", r.red(label+": "), d.Message)
+
+	lines := strings.Split(source, "\n")
+	span := d.Span
+
+	if span.StartLine < 1 || span.StartLine > len(lines) {
+		fmt.Fprintf(r.w, "%s\n", r.red(fmt.Sprintf("%s:%d:%d", span.File, span.StartLine, span.StartCol)))
+	} else {
+		line := strings.TrimSuffix(lines[span.StartLine-1], "\r")
+		fmt.Fprintln(r.w, span.File+":\n")
+		prefix := fmt.Sprintf("%d:%d | ", span.StartLine, span.StartCol)
+
+		fmt.Fprintf(r.w, "%s%s\n", r.red(prefix), line)
+
+		length := len([]rune(line)) - span.StartCol
+		if span.EndLine == span.StartLine {
+			length = span.EndCol - span.StartCol - 1
+		}
+
+		fmt.Fprint(r.w, strings.Repeat(" ", len(prefix))+padding(line, span.StartCol))
+		fmt.Fprint(r.w, r.red("^"))
+		fmt.Fprint(r.w, r.red(strings.Repeat("~", displayWidth(slice(line, span.StartCol, length)))))
+		fmt.Fprint(r.w, "\n")
+	}
+
+	r.renderTrace(d, lines)
+
+	for _, note := range d.Notes {
+		fmt.Fprintf(r.w, "%s%s\n", r.cyan("note: "), note)
+	}
+	for _, suggestion := range d.Suggestions {
+		fmt.Fprintf(r.w, "%s%s\n", r.cyan("help: "), suggestion)
+	}
+}
+
+// renderTrace prints the word calls that led to d, Python style: the
+// outermost call first, each with the word it happened in and its line of
+// source, ending at the error itself.
+func (r *Renderer) renderTrace(d *Diagnostic, lines []string) {
+	if len(d.Trace) == 0 {
+		return
+	}
+
+	fmt.Fprintf(r.w, "\n%s\n", r.cyan("Traceback (most recent call last):"))
+
+	in := "<main>"
+	for _, frame := range d.Trace {
+		r.renderFrame(frame.Loc, in, lines, d.Span.File)
+		in = frame.Name
+	}
+	r.renderFrame(d.Span.Start(), in, lines, d.Span.File)
+}
+
+func (r *Renderer) renderFrame(loc tokens.Loc, in string, lines []string, file string) {
+	fmt.Fprintf(r.w, "  %s:%d:%d, in %s\n", loc.File, loc.Line, loc.Col, in)
+	if loc.File == file && loc.Line >= 1 && loc.Line <= len(lines) {
+		fmt.Fprintf(r.w, "    %s\n", strings.TrimSpace(lines[loc.Line-1]))
+	}
+}
+
+// RenderAll prints every diagnostic in list. When stopped is set, the list
+// was cut short by an error limit and the human format says so.
+func (r *Renderer) RenderAll(list List, source string, stopped bool) {
+	for i, d := range list {
+		if i > 0 && r.format == FormatHuman {
+			fmt.Fprintln(r.w)
+		}
+		r.Render(d, source)
+	}
+
+	if r.format != FormatHuman {
+		return
+	}
+
+	noun := "warnings"
+	for _, d := range list {
+		if d.Severity == SeverityError {
+			noun = "errors"
+		}
+	}
+
+	switch {
+	case stopped:
+		fmt.Fprintf(r.w, "\n%s\n", r.red(fmt.Sprintf("Stopped after %d %s.", len(list), noun)))
+	case len(list) > 1:
+		fmt.Fprintf(r.w, "\n%s\n", r.red(fmt.Sprintf("Found %d %s.", len(list), noun)))
+	}
+}
+
+// isWide reports whether ch takes two terminal cells (East Asian wide and
+// fullwidth characters, emoji).
+func isWide(ch rune) bool {
+	return ch >= 0x1100 && ch <= 0x115F ||
+		ch >= 0x2E80 && ch <= 0xA4CF && ch != 0x303F ||
+		ch >= 0xAC00 && ch <= 0xD7A3 ||
+		ch >= 0xF900 && ch <= 0xFAFF ||
+		ch >= 0xFE30 && ch <= 0xFE4F ||
+		ch >= 0xFF00 && ch <= 0xFF60 ||
+		ch >= 0xFFE0 && ch <= 0xFFE6 ||
+		ch >= 0x1F300 && ch <= 0x1F64F ||
+		ch >= 0x1F900 && ch <= 0x1F9FF ||
+		ch >= 0x20000 && ch <= 0x3FFFD
+}
+
+func displayWidth(content string) int {
+	width := 0
+	for _, ch := range content {
+		switch {
+		case unicode.Is(unicode.Mn, ch) || unicode.Is(unicode.Me, ch) || ch == 0x200B:
+		case isWide(ch):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// padding returns the blank space that puts a caret under column col (in
+// runes) of line. Tabs are kept so the terminal expands them the same way it
+// did for the source line.
+func padding(line string, col int) string {
+	var sb strings.Builder
+	runes := []rune(line)
+	for i := 0; i < col-1; i++ {
+		if i < len(runes) && runes[i] == '\t' {
+			sb.WriteByte('\t')
+			continue
+		}
+		width := 1
+		if i < len(runes) {
+			width = displayWidth(string(runes[i]))
+		}
+		sb.WriteString(strings.Repeat(" ", width))
+	}
+	return sb.String()
+}
+
+// slice returns the text covered by length runes starting right after
+// column col of line.
+func slice(line string, col, length int) string {
+	runes := []rune(line)
+	start := min(max(col, 0), len(runes))
+	end := min(start+max(length, 0), len(runes))
+	return string(runes[start:end])
+}
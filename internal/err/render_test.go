@@ -0,0 +1,148 @@
+package err_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+const source = "define f\n    \"a\" 1 +\nend\nf"
+
+// typeMismatch is the error of running source: '+' on line 2, called
+// from line 4.
+func typeMismatch() *err.Diagnostic {
+	d := err.New(err.TypeMismatch, err.SpanAt(tokens.Loc{File: "t.brz", Line: 2, Col: 11}, 1), "Cannot add a string and an int.")
+	d.Trace = []err.Frame{{Name: "f", Loc: tokens.Loc{File: "t.brz", Line: 4, Col: 1}}}
+	return d
+}
+
+func render(d *err.Diagnostic, src string, color bool) string {
+	var out bytes.Buffer
+	err.NewRenderer(&out, err.FormatHuman, color).Render(d, src)
+	return out.String()
+}
+
+func TestRenderHuman(t *testing.T) {
+	got := render(typeMismatch(), source, false)
+	want := `RuntimeError[E302]: Cannot add a string and an int.
+
+t.brz:
+
+2:11 |     "a" 1 +
+                 ^
+
+Traceback (most recent call last):
+  t.brz:4:1, in <main>
+    f
+  t.brz:2:11, in f
+    "a" 1 +
+`
+	if got != want {
+		t.Errorf("Render =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderCaret(t *testing.T) {
+	cases := []struct {
+		name  string
+		line  string
+		col   int
+		len   int
+		caret string
+	}{
+		{"one rune", "1 x 2", 3, 1, "  ^"},
+		{"underline", "1 foo 2", 3, 3, "  ^~~"},
+		{"after multibyte", `"ção" foo`, 7, 3, "      ^~~"},
+		{"after wide", `"漢字" foo`, 6, 3, "       ^~~"},
+		{"after tab", "\t1 foo", 4, 3, "\t  ^~~"},
+		{"wide underline", `1 "漢字"`, 3, 4, "  ^~~~~~"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := err.New(err.InvalidCharacter, err.SpanAt(tokens.Loc{File: "t.brz", Line: 1, Col: c.col}, c.len), "x")
+			lines := strings.Split(render(d, c.line, false), "\n")
+			// The caret line follows the quoted line, after its prefix.
+			prefix := len(lines[4]) - len(c.line)
+			if got := lines[5][prefix:]; got != c.caret {
+				t.Errorf("caret = %q, want %q\n%s", got, c.caret, strings.Join(lines, "\n"))
+			}
+		})
+	}
+}
+
+func TestRenderNotes(t *testing.T) {
+	d := err.New(err.UnknownName, err.SpanAt(tokens.Loc{File: "t.brz", Line: 1, Col: 1}, 5), "Unknown word 'wrtie'.")
+	d.Note("words are defined with 'define'").Suggest("did you mean 'write'?")
+
+	got := render(d, "wrtie", false)
+	if !strings.HasSuffix(got, "\nnote: words are defined with 'define'\nhelp: did you mean 'write'?\n") {
+		t.Errorf("Render =\n%s\nwant it to end with the note and the help", got)
+	}
+}
+
+func TestRenderOutsideSource(t *testing.T) {
+	d := err.New(err.IOError, err.SpanAt(tokens.Loc{File: "t.brz", Line: 9, Col: 1}, 1), "Unable to read.")
+	if got, want := render(d, "1", false), "RuntimeError[E305]: Unable to read.\n\nt.brz:9:1\n"; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	d := typeMismatch()
+	d.Note("a note")
+
+	colored := render(d, source, true)
+	for _, want := range []string{
+		"\x1b[31mRuntimeError[E302]: \x1b[0m",
+		"\x1b[31m^\x1b[0m",
+		"\x1b[36mnote: \x1b[0m",
+		"\x1b[36mTraceback (most recent call last):\x1b[0m",
+	} {
+		if !strings.Contains(colored, want) {
+			t.Errorf("colored output doesn't hold %q:\n%q", want, colored)
+		}
+	}
+
+	if plain := render(d, source, false); strings.Contains(plain, "\x1b[") {
+		t.Errorf("output without color holds escapes:\n%q", plain)
+	}
+}
+
+func TestRenderAll(t *testing.T) {
+	first := err.New(err.InvalidCharacter, err.SpanAt(tokens.Loc{File: "t.brz", Line: 1, Col: 3}, 1), "Invalid character '$'.")
+	second := err.New(err.InvalidCharacter, err.SpanAt(tokens.Loc{File: "t.brz", Line: 1, Col: 5}, 1), "Invalid character '@'.")
+	warning := err.New("L105", err.SpanAt(tokens.Loc{File: "t.brz", Line: 1, Col: 1}, 3), "'dup' followed by 'pop' does nothing.")
+	warning.Severity = err.SeverityWarning
+
+	cases := []struct {
+		name    string
+		list    err.List
+		stopped bool
+		ending  string
+	}{
+		{"one error", err.List{first}, false, "1 $ @\n        ^\n"},
+		{"several errors", err.List{first, second}, false, "\n\nFound 2 errors.\n"},
+		{"stopped", err.List{first, second}, true, "\n\nStopped after 2 errors.\n"},
+		{"warnings", err.List{warning, warning}, false, "\n\nFound 2 warnings.\n"},
+		{"errors and warnings", err.List{warning, first}, false, "\n\nFound 2 errors.\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err.NewRenderer(&out, err.FormatHuman, false).RenderAll(c.list, "1 $ @", c.stopped)
+			got := out.String()
+			if !strings.HasSuffix(got, c.ending) {
+				t.Errorf("RenderAll =\n%s\nwant it to end with %q", got, c.ending)
+			}
+			// Diagnostics are separated by a blank line.
+			if n := strings.Count(got, "\n\nt.brz:\n\n"); n != len(c.list) {
+				t.Errorf("RenderAll printed %d diagnostics, want %d:\n%s", n, len(c.list), got)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Package format lays out Beremiz source the standard way: block bodies
+// indented by their nesting, one space between the words of a line, at
+// most one blank line in a row, and comments kept where they were.
+package format
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Indent is written once per open block at the start of a line.
+const Indent = "    "
+
+// line is a line of output.
+type line struct {
+	depth int
+	// parts are the words and comments of the line, written with a space
+	// between them.
+	parts []string
+	// comment is the comment ending the line, if any.
+	comment string
+	// blank marks an empty line.
+	blank bool
+}
+
+// code returns the line without its trailing comment.
+func (l line) code() string {
+	return strings.Repeat(Indent, l.depth) + strings.Join(l.parts, " ")
+}
+
+// width is how many runes the last line of the code takes, for lining up
+// trailing comments.
+func (l line) width() int {
+	code := l.code()
+	return utf8.RuneCountInString(code[strings.LastIndexByte(code, '\n')+1:])
+}
+
+// Source returns source formatted. Programs with lexer or block errors are
+// not formatted; their diagnostics are returned instead. Names that aren't
+// defined don't keep a file from being formatted.
+func Source(file, source string) (string, error) {
+	lex := lexer.New(source, file)
+	lex.SetKeepTrivia(true)
+	ts, e := lex.Tokenize()
+	if e != nil {
+		return "", e
+	}
+
+	p := parser.New(ts, false)
+	var errs err.List
+	for _, d := range err.Diagnostics(p.Check()) {
+		if d.Code != err.UnknownName {
+			errs = append(errs, d)
+		}
+	}
+	if e := errs.Err(); e != nil {
+		return "", e
+	}
+
+	lines := layout(source, ts, lex.Trivia(), p.Depths())
+	alignComments(lines)
+
+	var b strings.Builder
+	for _, l := range lines {
+		if !l.blank {
+			b.WriteString(l.code())
+			if l.comment != "" {
+				if len(l.parts) > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(l.comment)
+			}
+		}
+		b.WriteByte('\n')
+	}
+	formatted := b.String()
+
+	if e := same(file, ts, formatted); e != nil {
+		return "", e
+	}
+	return formatted, nil
+}
+
+// layout splits the program into output lines, keeping the line breaks of
+// the source.
+func layout(source string, ts []tokens.Token, trivia []tokens.Trivia, depths []int) []line {
+	runes := []rune(source)
+	pos := 0
+
+	var lines []line
+	var cur *line
+	blank := false
+
+	// start begins a line at depth, after a blank one if the source had
+	// one there.
+	start := func(depth int) {
+		if blank && len(lines) > 0 {
+			lines = append(lines, line{blank: true})
+		}
+		blank = false
+		lines = append(lines, line{depth: depth})
+		cur = &lines[len(lines)-1]
+	}
+
+	i, j := 0, 0
+	for i < len(ts) && ts[i].Type != tokens.EOF || j < len(trivia) {
+		if j >= len(trivia) || i < len(ts) && ts[i].Type != tokens.EOF && before(ts[i].Loc, trivia[j].Loc) {
+			token := ts[i]
+			text := string(runes[pos : pos+token.Len])
+			pos += token.Len
+
+			if cur == nil {
+				start(tokenDepth(token, depths[i]))
+			}
+			cur.parts = append(cur.parts, text)
+			i++
+			continue
+		}
+
+		t := trivia[j]
+		pos += utf8.RuneCountInString(t.Text)
+		j++
+
+		switch t.Kind {
+		case tokens.Whitespace:
+			if n := strings.Count(t.Text, "\n"); n > 0 {
+				cur = nil
+				blank = blank || n > 1
+			}
+
+		case tokens.LineComment:
+			if cur == nil {
+				start(commentDepth(ts, i, depths))
+				cur.parts = append(cur.parts, t.Text)
+			} else {
+				cur.comment = t.Text
+			}
+
+		case tokens.BlockComment:
+			if cur == nil {
+				start(commentDepth(ts, i, depths))
+			}
+			cur.parts = append(cur.parts, t.Text)
+		}
+	}
+
+	return lines
+}
+
+// before reports whether a comes before b in the source.
+func before(a, b tokens.Loc) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+}
+
+// tokenDepth returns how deep a line starting with token is indented:
+// 'do', 'elif' and 'else' line up with the keyword opening their block.
+func tokenDepth(token tokens.Token, depth int) int {
+	switch token.Type {
+	case tokens.Do, tokens.Elif, tokens.Else:
+		return max(depth-1, 0)
+	}
+	return depth
+}
+
+// commentDepth returns how deep a comment on its own line is indented: like
+// the body it is in, found from the next token.
+func commentDepth(ts []tokens.Token, next int, depths []int) int {
+	if next >= len(ts) || ts[next].Type == tokens.EOF {
+		return 0
+	}
+	if ts[next].Type == tokens.End {
+		return depths[next] + 1
+	}
+	return depths[next]
+}
+
+// alignComments lines up the trailing comments of consecutive lines.
+func alignComments(lines []line) {
+	for i := 0; i < len(lines); {
+		if lines[i].comment == "" || len(lines[i].parts) == 0 {
+			i++
+			continue
+		}
+
+		j, width := i, 0
+		for ; j < len(lines) && lines[j].comment != "" && len(lines[j].parts) > 0; j++ {
+			width = max(width, lines[j].width())
+		}
+		for k := i; k < j; k++ {
+			lines[k].parts[len(lines[k].parts)-1] += strings.Repeat(" ", width-lines[k].width())
+		}
+		i = j
+	}
+}
+
+// same checks that formatted holds the same program as ts, so formatting
+// can never change what a program does.
+func same(file string, ts []tokens.Token, formatted string) error {
+	got, e := lexer.New(formatted, file).Tokenize()
+	if e != nil || len(got) != len(ts) {
+		return fmt.Errorf("%s: formatting would change the program", file)
+	}
+	for i := range ts {
+		if got[i].Type != ts[i].Type || got[i].Literal != ts[i].Literal {
+			return fmt.Errorf("%s:%d:%d: formatting would change the program", file, ts[i].Loc.Line, ts[i].Loc.Col)
+		}
+	}
+	return nil
+}
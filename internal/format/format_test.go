@@ -0,0 +1,106 @@
+package format_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/format"
+)
+
+func TestSource(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"spaces", "1   2\t+  writeln", "1 2 + writeln\n"},
+		{
+			"nested blocks",
+			"define f\nfor dup 0 > do\nif dup 2 % 0 eq do\n\"even\" writeln\nend\n1 -\nend\nend\n",
+			"define f\n    for dup 0 > do\n        if dup 2 % 0 eq do\n            \"even\" writeln\n        end\n        1 -\n    end\nend\n",
+		},
+		{
+			"elif and else",
+			"if true do\n1\n  elif false do\n2\n      else\n3\nend\n",
+			"if true do\n    1\nelif false do\n    2\nelse\n    3\nend\n",
+		},
+		{
+			"do on its own line",
+			"for dup 0 >\ndo\n1 -\nend\n",
+			"for dup 0 >\ndo\n    1 -\nend\n",
+		},
+		{
+			"comments",
+			"# header\ndefine f\n# inside\n  1   # one\n# before end\nend\n",
+			"# header\ndefine f\n    # inside\n    1 # one\n    # before end\nend\n",
+		},
+		{
+			"aligned comments",
+			"1 # one\n10 20 # two\n\n3 # three\n",
+			"1     # one\n10 20 # two\n\n3 # three\n",
+		},
+		{
+			"block comment",
+			"define f\n#[ a\nb # 1\nend\n",
+			"define f\n    #[ a\nb # 1\nend\n",
+		},
+		{
+			"blank lines",
+			"\n\n1\n\n\n\n2\n\n\n",
+			"1\n\n2\n",
+		},
+		{
+			"strings",
+			"r\"a\\b\"   'it\\'s'\n\"\"\"x\n      y\"\"\"    r'''^\\d+$'''\n",
+			"r\"a\\b\" 'it\\'s'\n\"\"\"x\n      y\"\"\" r'''^\\d+$'''\n",
+		},
+		{
+			"string in a block",
+			"define f\n\"\"\"a\nb\"\"\" writeln\nend\n",
+			"define f\n    \"\"\"a\nb\"\"\" writeln\nend\n",
+		},
+		{"unknown names", "foo  bar", "foo bar\n"},
+		{"empty", "", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, e := format.Source("t.brz", c.source)
+			if e != nil {
+				t.Fatalf("Source: %v", e)
+			}
+			if got != c.want {
+				t.Fatalf("Source =\n%q\nwant\n%q", got, c.want)
+			}
+
+			// Formatting twice changes nothing.
+			again, e := format.Source("t.brz", got)
+			if e != nil || again != got {
+				t.Errorf("formatting again = %q, %v; want %q", again, e, got)
+			}
+		})
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		code   err.Code
+	}{
+		{"lexer error", "1 ( 2", err.InvalidCharacter},
+		{"unclosed block", "define f 1", err.UnclosedBlock},
+		{"stray end", "1 end", err.InvalidBlock},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, e := format.Source("t.brz", c.source)
+			var d *err.Diagnostic
+			if !errors.As(e, &d) || d.Code != c.code {
+				t.Errorf("Source: got %v, want a %s diagnostic", e, c.code)
+			}
+		})
+	}
+}
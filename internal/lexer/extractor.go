@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/adaiasmagdiel/beremiz-go/internal/err"
 	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
@@ -53,55 +54,48 @@ loop:
 			if isInt {
 				isInt = false
 			} else {
-				err.LexerError(l.lines, tokens.Loc{File: l.file, Line: l.line, Col: max(l.col-1, 0)},
-					"More than one decimal point in number.", 0)
-				l.errorHandler()
+				l.fail(err.InvalidNumber, l.getLoc(),
+					"More than one decimal point in number.", 1)
 				break loop
 			}
 
 		case ch == 'x' || ch == 'X':
 			if isHex || len(literal) != 1 || literal[0] != '0' {
-				err.LexerError(l.lines, tokens.Loc{File: l.file, Line: l.line, Col: max(l.col-1, 0)},
-					fmt.Sprintf("Invalid hexadecimal literal: expected '0' before '%c', but found '%c'.", ch, l.prev()), 0)
-				l.errorHandler()
+				l.fail(err.InvalidNumber, l.getLoc(),
+					fmt.Sprintf("Invalid hexadecimal literal: expected '0' before '%c', but found '%c'.", ch, l.prev()), 1)
 				break loop
 			}
 			isHex = true
 
 		case ch == 'o' || ch == 'O':
 			if isOctal || len(literal) != 1 || literal[0] != '0' {
-				err.LexerError(l.lines, tokens.Loc{File: l.file, Line: l.line, Col: max(l.col-1, 0)},
-					fmt.Sprintf("Invalid octal literal: expected '0' before '%c', but found '%c'.", ch, l.prev()), 0)
-				l.errorHandler()
+				l.fail(err.InvalidNumber, l.getLoc(),
+					fmt.Sprintf("Invalid octal literal: expected '0' before '%c', but found '%c'.", ch, l.prev()), 1)
 				break loop
 			}
 			isOctal = true
 
 		case !isHex && (ch == 'b' || ch == 'B'):
 			if isBinary || len(literal) != 1 || literal[0] != '0' {
-				err.LexerError(l.lines, tokens.Loc{File: l.file, Line: l.line, Col: max(l.col-1, 0)},
-					fmt.Sprintf("Invalid binary literal: expected '0' before '%c', but found '%c'.", ch, l.prev()), 0)
-				l.errorHandler()
+				l.fail(err.InvalidNumber, l.getLoc(),
+					fmt.Sprintf("Invalid binary literal: expected '0' before '%c', but found '%c'.", ch, l.prev()), 1)
 				break loop
 			}
 			isBinary = true
 
 		case isHex && !l.isValidHexadecimal(ch) && l.isAlpha(ch):
-			err.LexerError(l.lines, tokens.Loc{File: l.file, Line: l.line, Col: max(l.col-1, 0)},
-				fmt.Sprintf("Invalid character '%c' in hexadecimal literal.", ch), 0)
-			l.errorHandler()
+			l.fail(err.InvalidNumber, l.getLoc(),
+				fmt.Sprintf("Invalid character '%c' in hexadecimal literal.", ch), 1)
 			break loop
 
 		case isOctal && !l.isValidOctal(ch) && l.isAlpha(ch):
-			err.LexerError(l.lines, tokens.Loc{File: l.file, Line: l.line, Col: max(l.col-1, 0)},
-				fmt.Sprintf("Invalid character '%c' in octal literal.", ch), 0)
-			l.errorHandler()
+			l.fail(err.InvalidNumber, l.getLoc(),
+				fmt.Sprintf("Invalid character '%c' in octal literal.", ch), 1)
 			break loop
 
 		case isBinary && !l.isValidBinary(ch) && l.isAlpha(ch):
-			err.LexerError(l.lines, tokens.Loc{File: l.file, Line: l.line, Col: max(l.col-1, 0)},
-				fmt.Sprintf("Invalid character '%c' in binary literal.", ch), 0)
-			l.errorHandler()
+			l.fail(err.InvalidNumber, l.getLoc(),
+				fmt.Sprintf("Invalid character '%c' in binary literal.", ch), 1)
 			break loop
 
 		case !l.isNum(ch) && !isHex && !isOctal:
@@ -134,8 +128,8 @@ loop:
 		n, e := strconv.ParseInt(literal, base, 64)
 		if e != nil {
 			n = 0.0
-			err.Error(fmt.Sprintf("Unable to convert literal '%s' to int64.", literal))
-			l.errorHandler()
+			l.fail(err.InvalidNumber, tokens.Loc{File: l.file, Line: line, Col: col},
+				fmt.Sprintf("Unable to convert literal '%s' to int64.", literal), l.col-col)
 		}
 
 		return tokens.Token{
@@ -147,8 +141,8 @@ loop:
 		n, e := strconv.ParseFloat(literal, 64)
 		if e != nil {
 			n = 0.0
-			err.Error(fmt.Sprintf("Unable to convert literal '%s' to float64.", literal))
-			l.errorHandler()
+			l.fail(err.InvalidNumber, tokens.Loc{File: l.file, Line: line, Col: col},
+				fmt.Sprintf("Unable to convert literal '%s' to float64.", literal), l.col-col)
 		}
 		if isNegative {
 			n *= -1
@@ -181,7 +175,22 @@ func (l *Lexer) extractIdentifier() tokens.Token {
 		l.consume()
 	}
 
-	literal = l.content[start:l.pos]
+	// Dashes aren't part of names, so a-b is a minus between two words,
+	// except in the built-in words written with one, like read-line.
+	for !l.isAtEnd() && l.peek() == '-' {
+		end := l.pos + 1
+		for end < len(l.content) && l.isValidIdentifier(l.content[end]) {
+			end++
+		}
+		if !tokens.IsKeyword(string(l.content[start:end])) {
+			break
+		}
+		for l.pos < end {
+			l.consume()
+		}
+	}
+
+	literal = string(l.content[start:l.pos])
 	kind := tokens.Identifier
 
 	if tokens.IsKeyword(literal) {
@@ -205,7 +214,7 @@ func (l *Lexer) extractComment() tokens.Token {
 	l.consume() // Remove #
 
 	isMultiline := false
-	if l.peek() == '[' {
+	if !l.isAtEnd() && l.peek() == '[' {
 		isMultiline = true
 	}
 
@@ -236,53 +245,65 @@ func (l *Lexer) extractComment() tokens.Token {
 }
 
 func (l *Lexer) extractString() tokens.Token {
-	var stringType byte = l.consume() // Remove ' or "
-	var literal string = ""
-
 	line := l.line
 	col := l.col
 
+	var isRaw bool = false
+	if l.peek() == 'r' {
+		isRaw = true
+		l.consume()
+	}
+
+	var delimiter string = string(l.consume()) // Remove ' or "
+	if l.startsWith(delimiter + delimiter) {
+		l.consume()
+		l.consume()
+		delimiter = strings.Repeat(delimiter, 3)
+	}
+	isMultiline := len(delimiter) == 3
+
 	var start int = l.pos
 	for {
-		if l.isAtEnd() {
-			err.LexerError(l.lines, tokens.Loc{
+		if l.isAtEnd() || l.peek() == '\n' && !isMultiline {
+			l.fail(err.UnterminatedString, tokens.Loc{
 				File: l.file,
 				Line: line,
-				Col:  col - 1,
-			}, "Unterminated string literal.", l.col-col)
-			l.errorHandler()
+				Col:  col,
+			}, "Unterminated string literal.", utf8.RuneCountInString(l.lines[line-1])-col+1)
 			break
 		}
 
-		ch := l.peek()
-
-		// Disallow multiline strings here
-		if ch == '\n' {
-			err.LexerError(l.lines, tokens.Loc{
-				File: l.file,
-				Line: line,
-				Col:  col - 1,
-			}, "Unterminated string literal.", l.col-col)
-			l.errorHandler()
+		if l.startsWith(delimiter) {
 			break
 		}
 
-		if ch == stringType {
-			if l.prev() != '\\' {
-				l.consume() // Remove ' or "
-				break
-			}
+		// Skip the escaped character so an escaped quote doesn't end the literal
+		if l.peek() == '\\' && !isRaw && l.pos+1 < len(l.content) {
+			l.consume()
 		}
 
 		l.consume()
 	}
 
-	literal = l.content[start : l.pos-1]
+	literal := string(l.content[start:l.pos])
+	if l.startsWith(delimiter) {
+		for range len(delimiter) {
+			l.consume() // Remove ' or "
+		}
+	}
 
-	parsed, e := strconv.Unquote(`"` + literal + `"`)
+	if isRaw {
+		return tokens.Token{
+			Type:    tokens.String,
+			Literal: literal,
+			Loc:     tokens.Loc{File: l.file, Line: line, Col: col},
+		}
+	}
+
+	parsed, e := unescape(literal)
 	if e != nil {
-		err.Error("Unable to parse string literal.")
-		l.errorHandler()
+		l.fail(err.InvalidEscape, tokens.Loc{File: l.file, Line: line, Col: col},
+			"Unable to parse string literal: invalid escape sequence.", utf8.RuneCountInString(literal)+2*len(delimiter))
 	}
 
 	return tokens.Token{
@@ -291,3 +312,32 @@ func (l *Lexer) extractString() tokens.Token {
 		Loc:     tokens.Loc{File: l.file, Line: line, Col: col},
 	}
 }
+
+// unescape interprets Go-style escape sequences. Both \' and \" are accepted
+// regardless of the delimiter, so quotes never need escaping in the other
+// quote style.
+func unescape(literal string) (string, error) {
+	var sb strings.Builder
+
+	for len(literal) > 0 {
+		if strings.HasPrefix(literal, `\'`) || strings.HasPrefix(literal, `\"`) {
+			sb.WriteByte(literal[1])
+			literal = literal[2:]
+			continue
+		}
+
+		value, multibyte, tail, e := strconv.UnquoteChar(literal, 0)
+		if e != nil {
+			return "", e
+		}
+
+		if value < utf8.RuneSelf || multibyte {
+			sb.WriteRune(value)
+		} else {
+			sb.WriteByte(byte(value))
+		}
+		literal = tail
+	}
+
+	return sb.String(), nil
+}
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/adaiasmagdiel/beremiz-go/internal/err"
@@ -8,29 +9,112 @@ import (
 )
 
 type Lexer struct {
-	content      string
-	file         string
-	lines        []string
-	pos          int
-	col          int
-	line         int
-	errorHandler func()
-}
-
-func New(content string, file string, errorHandler func()) *Lexer {
-	if errorHandler == nil {
-		errorHandler = func() {}
-	}
+	content []rune
+	file    string
+	lines   []string
+	pos     int
+	col     int
+	line    int
+	errs    err.List
+	// maxErrors stops tokenizing once that many errors were found; 0 means
+	// no limit.
+	maxErrors int
+	// bad is set when the token being read has an error, so only the first
+	// one is reported and the rest of the token is skipped.
+	bad bool
+	// keepTrivia makes Tokenize record whitespace and comments in trivia.
+	keepTrivia bool
+	trivia     []tokens.Trivia
+	// triviaEnd is where the last trivia ends, in runes.
+	triviaEnd int
+}
 
+func New(content string, file string) *Lexer {
 	return &Lexer{
-		content:      content,
-		file:         file,
-		lines:        strings.Split(content, "\n"),
-		pos:          0,
-		col:          1,
-		line:         1,
-		errorHandler: errorHandler,
+		content: []rune(content),
+		file:    file,
+		lines:   strings.Split(content, "\n"),
+		pos:     0,
+		col:     1,
+		line:    1,
+	}
+}
+
+// ParseNumber parses content using the same rules as number literals in
+// source code. Surrounding whitespace is ignored.
+func ParseNumber(content string) (tokens.Token, error) {
+	content = strings.TrimSpace(content)
+	l := New(content, "stdin")
+	if l.isAtEnd() || !l.isNumberStart() {
+		return tokens.Token{}, fmt.Errorf("'%s' is not a number", content)
+	}
+
+	token := l.extractNumber()
+	if len(l.errs) > 0 || !l.isAtEnd() {
+		return tokens.Token{}, fmt.Errorf("'%s' is not a number", content)
+	}
+
+	return token, nil
+}
+
+// SetMaxErrors makes Tokenize stop after n errors. By default every error in
+// the source is reported.
+func (l *Lexer) SetMaxErrors(n int) {
+	l.maxErrors = n
+}
+
+// SetKeepTrivia makes Tokenize keep the whitespace and comments between
+// tokens, returned by Trivia. They are thrown away by default.
+func (l *Lexer) SetKeepTrivia(keep bool) {
+	l.keepTrivia = keep
+}
+
+// Trivia returns the whitespace and comments found by Tokenize, in source
+// order, when SetKeepTrivia was called. Together with the tokens they cover
+// the whole source of a program without errors.
+func (l *Lexer) Trivia() []tokens.Trivia {
+	return l.trivia
+}
+
+// addTrivia records the source read since start, at loc, as trivia.
+// Whitespace right after whitespace is merged into it.
+func (l *Lexer) addTrivia(kind tokens.TriviaKind, loc tokens.Loc, start int) {
+	if !l.keepTrivia {
+		return
+	}
+
+	text := string(l.content[start:l.pos])
+	n := len(l.trivia)
+	if kind == tokens.Whitespace && n > 0 && l.trivia[n-1].Kind == tokens.Whitespace && l.triviaEnd == start {
+		l.trivia[n-1].Text += text
+	} else {
+		l.trivia = append(l.trivia, tokens.Trivia{Kind: kind, Text: text, Loc: loc})
+	}
+	l.triviaEnd = l.pos
+}
+
+// fail records a lexer error spanning length runes from loc. Only the first
+// error of each token is kept.
+func (l *Lexer) fail(code err.Code, loc tokens.Loc, message string, length int) {
+	if l.bad {
+		return
 	}
+
+	l.bad = true
+	l.errs = append(l.errs, err.New(code, err.SpanAt(loc, length), message))
+}
+
+// tooManyErrors reports whether the error limit was reached.
+func (l *Lexer) tooManyErrors() bool {
+	return l.maxErrors > 0 && len(l.errs) >= l.maxErrors
+}
+
+// recover skips the rest of a bad token, up to the next whitespace.
+func (l *Lexer) recover() {
+	for !l.isAtEnd() && !l.isWhitespace(l.peek()) {
+		l.consume()
+	}
+	l.bad = false
 }
 
 func (l *Lexer) getLoc() tokens.Loc {
@@ -41,11 +125,11 @@ func (l *Lexer) getLoc() tokens.Loc {
 	}
 }
 
-func (l *Lexer) peek() byte {
+func (l *Lexer) peek() rune {
 	return l.content[l.pos]
 }
 
-func (l *Lexer) prev() byte {
+func (l *Lexer) prev() rune {
 	if l.pos-1 >= 0 {
 		return l.content[l.pos-1]
 	} else {
@@ -53,7 +137,7 @@ func (l *Lexer) prev() byte {
 	}
 }
 
-func (l *Lexer) next() byte {
+func (l *Lexer) next() rune {
 	if l.pos+1 >= len(l.content) {
 		return 0
 	} else {
@@ -61,20 +145,32 @@ func (l *Lexer) next() byte {
 	}
 }
 
-func (l *Lexer) consume() byte {
+func (l *Lexer) consume() rune {
 	ch := l.peek()
 
 	l.pos++
-	l.col++
 
-	if !l.isAtEnd() && l.peek() == '\n' {
+	if ch == '\n' {
 		l.col = 1
 		l.line++
+	} else {
+		l.col++
 	}
 
 	return ch
 }
 
+func (l *Lexer) startsWith(prefix string) bool {
+	idx := l.pos
+	for _, ch := range prefix {
+		if idx >= len(l.content) || l.content[idx] != ch {
+			return false
+		}
+		idx++
+	}
+	return true
+}
+
 func (l *Lexer) isAtEnd() bool {
 	return l.pos >= len(l.content)
 }
@@ -83,20 +179,19 @@ func (l *Lexer) GetLines() []string {
 	return l.lines
 }
 
-func (l *Lexer) Tokenize() []tokens.Token {
+func (l *Lexer) Tokenize() ([]tokens.Token, error) {
 	var ts = []tokens.Token{}
 
 	for {
-		if l.isAtEnd() {
+		if l.isAtEnd() || l.tooManyErrors() {
 			break
 		}
 
 		ch := l.peek()
+		start, count := l.pos, len(ts)
+		loc := l.getLoc()
 
-		if l.isNum(ch) ||
-			ch == '.' && l.isNum(l.next()) ||
-			ch == '-' && l.isNum(l.next()) ||
-			ch == '+' && l.isNum(l.next()) {
+		if l.isNumberStart() {
 			token := l.extractNumber()
 			ts = append(ts, token)
 		} else if tokens.IsOperator(ch) {
@@ -126,27 +221,44 @@ func (l *Lexer) Tokenize() []tokens.Token {
 				l.consume()
 				l.consume()
 			} else {
+				loc := l.getLoc()
 				ts = append(ts, tokens.Token{
 					Type:    tokens.Operators[string(ch)],
 					Literal: string(l.consume()),
-					Loc:     l.getLoc(),
+					Loc:     loc,
 				})
 			}
+		} else if ch == 'r' && (l.next() == '\'' || l.next() == '"') {
+			token := l.extractString()
+			ts = append(ts, token)
 		} else if l.isAlpha(ch) || ch == '_' {
 			token := l.extractIdentifier()
 			ts = append(ts, token)
 		} else if ch == '#' {
+			kind := tokens.LineComment
+			if l.next() == '[' {
+				kind = tokens.BlockComment
+			}
 			l.extractComment()
+			l.addTrivia(kind, loc, start)
 		} else if ch == '\'' || ch == '"' {
 			token := l.extractString()
 			ts = append(ts, token)
 		} else if l.isWhitespace(ch) {
 			l.consume()
+			l.addTrivia(tokens.Whitespace, loc, start)
 		} else {
-			err.LexerError(l.lines, l.getLoc(), "invalid character '"+string(ch)+"'", 0)
-			l.errorHandler()
+			l.fail(err.InvalidCharacter, l.getLoc(), "invalid character '"+string(ch)+"'", 1)
 			l.consume()
 		}
+
+		if len(ts) > count {
+			ts[count].Len = l.pos - start
+		}
+
+		if l.bad {
+			l.recover()
+		}
 	}
 
 	ts = append(ts, tokens.Token{
@@ -155,5 +267,5 @@ func (l *Lexer) Tokenize() []tokens.Token {
 		Loc:     l.getLoc(),
 	})
 
-	return ts
+	return ts, l.errs.Err()
 }
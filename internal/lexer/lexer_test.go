@@ -0,0 +1,196 @@
+package lexer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// tokenize lexes source and fails the test on any error.
+func tokenize(t *testing.T, source string) []tokens.Token {
+	t.Helper()
+	ts, e := lexer.New(source, "test.brz").Tokenize()
+	if e != nil {
+		t.Fatalf("Tokenize(%q): %v", source, e)
+	}
+	return ts
+}
+
+// diagnostic lexes source and returns its only error.
+func diagnostic(t *testing.T, source string) *err.Diagnostic {
+	t.Helper()
+	_, e := lexer.New(source, "test.brz").Tokenize()
+	ds := err.Diagnostics(e)
+	if len(ds) != 1 {
+		t.Fatalf("Tokenize(%q): got %d errors, want 1: %v", source, len(ds), e)
+	}
+	return ds[0]
+}
+
+func TestStrings(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"escapes", `"a\tb\n"`, "a\tb\n"},
+		{"unicode escape", `"\u00e1"`, "á"},
+		{"single quoted", `'say "hi"'`, `say "hi"`},
+		{"escaped quote", `'it\'s'`, "it's"},
+		{"escaped delimiter", `"a \"b\" c"`, `a "b" c`},
+		{"raw", `r"C:\path\no-escape"`, `C:\path\no-escape`},
+		{"raw single quoted", `r'\d+'`, `\d+`},
+		{"triple quoted", "\"\"\"two\nlines\"\"\"", "two\nlines"},
+		{"triple single quoted", `'''a "b" 'c' d'''`, `a "b" 'c' d`},
+		{"triple quoted escapes", `"""a\tb"""`, "a\tb"},
+		{"raw triple quoted", `r'''^\d+$'''`, `^\d+$`},
+		{"empty", `""`, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := tokenize(t, c.source)
+			if len(ts) != 2 || ts[0].Type != tokens.String {
+				t.Fatalf("got %v, want a single string", ts)
+			}
+			if ts[0].Literal != c.want {
+				t.Errorf("literal = %q, want %q", ts[0].Literal, c.want)
+			}
+			if ts[0].Len != len([]rune(c.source)) {
+				t.Errorf("len = %d, want %d", ts[0].Len, len([]rune(c.source)))
+			}
+		})
+	}
+}
+
+func TestLocationAfterMultilineString(t *testing.T) {
+	ts := tokenize(t, "\"\"\"one\ntwo\nthree\"\"\" writeln\n1")
+
+	want := []tokens.Loc{
+		{File: "test.brz", Line: 1, Col: 1},
+		{File: "test.brz", Line: 3, Col: 10},
+		{File: "test.brz", Line: 4, Col: 1},
+	}
+	for i, loc := range want {
+		if ts[i].Loc != loc {
+			t.Errorf("token %d (%v) at %d:%d, want %d:%d", i, ts[i].Literal, ts[i].Loc.Line, ts[i].Loc.Col, loc.Line, loc.Col)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		code   err.Code
+		col    int
+	}{
+		{"unterminated", `1 "abc`, err.UnterminatedString, 3},
+		{"newline in a plain string", "\"abc\ndef", err.UnterminatedString, 1},
+		{"unterminated triple quoted", "'''abc\n", err.UnterminatedString, 1},
+		{"invalid escape", `1 "\q"`, err.InvalidEscape, 3},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := diagnostic(t, c.source)
+			if d.Code != c.code || d.Span.StartCol != c.col {
+				t.Errorf("got %s at column %d, want %s at column %d", d.Code, d.Span.StartCol, c.code, c.col)
+			}
+		})
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	cases := []struct {
+		source string
+		want   string
+	}{
+		{"área", "área"},
+		{"ação_total", "ação_total"},
+		{"_contador2", "_contador2"},
+		{"naïve", "naïve"},
+		{"cafe\u0301", "cafe\u0301"},
+		{"число", "число"},
+		{"変数", "変数"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.source, func(t *testing.T) {
+			ts := tokenize(t, c.source)
+			if len(ts) != 2 || ts[0].Type != tokens.Identifier || ts[0].Literal != c.want {
+				t.Fatalf("got %v, want the identifier %q", ts, c.want)
+			}
+		})
+	}
+
+	ts := tokenize(t, "define área 3 end")
+	if ts[0].Type != tokens.Define || ts[1].Type != tokens.Identifier || ts[1].Literal != "área" {
+		t.Errorf("got %v, want 'define' followed by the name 'área'", ts)
+	}
+}
+
+func TestDashes(t *testing.T) {
+	cases := []struct {
+		source string
+		want   []tokens.TokenType
+	}{
+		{"a-b", []tokens.TokenType{tokens.Identifier, tokens.Minus, tokens.Identifier}},
+		{"a-", []tokens.TokenType{tokens.Identifier, tokens.Minus}},
+		{"-a", []tokens.TokenType{tokens.Minus, tokens.Identifier}},
+		{"x-1", []tokens.TokenType{tokens.Identifier, tokens.Int}},
+		{"read-line", []tokens.TokenType{tokens.ReadLine}},
+		{"assert-throws", []tokens.TokenType{tokens.AssertThrows}},
+		{"read-lines", []tokens.TokenType{tokens.Identifier, tokens.Minus, tokens.Identifier}},
+		{"my-read-line", []tokens.TokenType{tokens.Identifier, tokens.Minus, tokens.ReadLine}},
+		{"read-line-x", []tokens.TokenType{tokens.ReadLine, tokens.Minus, tokens.Identifier}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.source, func(t *testing.T) {
+			ts := tokenize(t, c.source)
+			var got []tokens.TokenType
+			for _, token := range ts[:len(ts)-1] {
+				got = append(got, token.Type)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestColumnsCountRunes(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		cols   []int
+	}{
+		{"accented identifier", "área 1", []int{1, 6}},
+		{"accented string", `"ção" writeln`, []int{1, 7}},
+		{"emoji", `"🎉🎉" 1 2`, []int{1, 6, 8}},
+		{"combining mark", "e\u0301 x", []int{1, 4}},
+		{"comment", "# café\n  x", []int{3}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := tokenize(t, c.source)
+			for i, col := range c.cols {
+				if ts[i].Loc.Col != col {
+					t.Errorf("token %d (%v) at column %d, want %d", i, ts[i].Literal, ts[i].Loc.Col, col)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorColumnAfterMultibyte(t *testing.T) {
+	d := diagnostic(t, "\"olá\" ação $")
+	if d.Code != err.InvalidCharacter || d.Span.StartCol != 12 || d.Span.EndCol != 13 {
+		t.Errorf("got %s at columns %d-%d, want %s at 12-13", d.Code, d.Span.StartCol, d.Span.EndCol, err.InvalidCharacter)
+	}
+}
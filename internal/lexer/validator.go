@@ -1,37 +1,43 @@
 package lexer
 
-func (l *Lexer) isWhitespace(ch byte) bool {
-	switch ch {
-	case ' ', '\t', '\n', '\r', '\f', '\v':
-		return true
-	}
-	return false
+import "unicode"
+
+func (l *Lexer) isWhitespace(ch rune) bool {
+	return unicode.IsSpace(ch)
 }
 
-func (l *Lexer) isNum(ch byte) bool {
+func (l *Lexer) isNum(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
-func (l *Lexer) isAlpha(ch byte) bool {
-	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+func (l *Lexer) isAlpha(ch rune) bool {
+	return unicode.IsLetter(ch)
+}
+
+func (l *Lexer) isAlphaNum(ch rune) bool {
+	return l.isAlpha(ch) || unicode.IsDigit(ch)
 }
 
-func (l *Lexer) isAlphaNum(ch byte) bool {
-	return l.isAlpha(ch) || l.isNum(ch)
+func (l *Lexer) isValidIdentifier(ch rune) bool {
+	return l.isAlphaNum(ch) || unicode.Is(unicode.Mn, ch) || ch == '_'
 }
 
-func (l *Lexer) isValidIdentifier(ch byte) bool {
-	return l.isAlphaNum(ch) || ch == '_'
+func (l *Lexer) isNumberStart() bool {
+	ch := l.peek()
+	return l.isNum(ch) ||
+		ch == '.' && l.isNum(l.next()) ||
+		ch == '-' && l.isNum(l.next()) ||
+		ch == '+' && l.isNum(l.next())
 }
 
-func (l *Lexer) isValidHexadecimal(ch byte) bool {
+func (l *Lexer) isValidHexadecimal(ch rune) bool {
 	return l.isNum(ch) || ch >= 'a' && ch <= 'f' || ch >= 'A' && ch <= 'F'
 }
 
-func (l *Lexer) isValidOctal(ch byte) bool {
+func (l *Lexer) isValidOctal(ch rune) bool {
 	return ch >= '0' && ch <= '7'
 }
 
-func (l *Lexer) isValidBinary(ch byte) bool {
+func (l *Lexer) isValidBinary(ch rune) bool {
 	return ch == '0' || ch == '1'
 }
@@ -0,0 +1,195 @@
+// Package lint warns about Beremiz code that runs but is probably not what
+// was meant. Each warning comes from a Rule, which can be turned off for a
+// whole run through a Config or for one line with a comment:
+//
+//	dup pop # lint:ignore dup-pop
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/analysis"
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Rule is one kind of warning.
+type Rule struct {
+	// ID names the rule in configs and ignore comments, as does Code.
+	ID   string
+	Code err.Code
+	Doc  string
+
+	check func(p *pass)
+}
+
+// Rules lists every rule, in the order of their codes.
+var Rules = []*Rule{
+	{"unused-define", "L101", "A word is defined but never used.", unusedDefine},
+	{"shadow-builtin", "L102", "A word is named like a built-in, differing only in case or '_' for '-'.", shadowBuiltin},
+	{"constant-condition", "L103", "The condition of an 'if', 'elif' or 'for' is made only of literals.", constantCondition},
+	{"unreachable-code", "L104", "Code follows a loop whose condition is always true and that never calls 'exit'.", unreachableCode},
+	{"dup-pop", "L105", "A value copied by 'dup' is dropped by a 'pop' without being used.", dupPop},
+	{"unbalanced-branch", "L106", "The branches of an 'if' leave different stack depths.", unbalancedBranch},
+	{"redefined-word", "L107", "A word is defined more than once; only the last definition is used.", redefinedWord},
+}
+
+// Find returns the rule with the given ID or code.
+func Find(name string) (*Rule, bool) {
+	for _, r := range Rules {
+		if r.ID == name || string(r.Code) == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// Config chooses which rules run.
+type Config struct {
+	// Disable lists the rules turned off, by ID or code.
+	Disable []string `json:"disable"`
+}
+
+// LoadConfig reads a Config from the JSON file at path, such as
+//
+//	{"disable": ["dup-pop", "L101"]}
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	content, e := os.ReadFile(path)
+	if e != nil {
+		return config, e
+	}
+	if e := json.Unmarshal(content, &config); e != nil {
+		return config, fmt.Errorf("%s: %w", path, e)
+	}
+	if e := config.Validate(); e != nil {
+		return config, fmt.Errorf("%s: %w", path, e)
+	}
+	return config, nil
+}
+
+// Validate checks that every rule config names exists.
+func (c Config) Validate() error {
+	for _, name := range c.Disable {
+		if _, ok := Find(name); !ok {
+			return fmt.Errorf("unknown lint rule '%s'", name)
+		}
+	}
+	return nil
+}
+
+// enabled reports whether r runs under c.
+func (c Config) enabled(r *Rule) bool {
+	return !slices.ContainsFunc(c.Disable, func(name string) bool {
+		found, ok := Find(name)
+		return ok && found == r
+	})
+}
+
+// pass is the state of linting one program.
+type pass struct {
+	ts     []tokens.Token
+	depths []int
+	defs   []analysis.Definition
+
+	rule     *Rule
+	warnings err.List
+}
+
+// warn reports a warning from the current rule at token.
+func (p *pass) warn(token tokens.Token, format string, args ...any) *err.Diagnostic {
+	d := err.New(p.rule.Code, err.TokenSpan(token), fmt.Sprintf(format, args...))
+	d.Severity = err.SeverityWarning
+	p.warnings = append(p.warnings, d)
+	return d
+}
+
+// Lint returns the warnings for source, in source order. Programs with
+// lexer or block errors aren't linted; their diagnostics are returned as
+// the error instead. Names that aren't defined are left to the
+// interpreter, since they may be host words.
+func Lint(file, source string, config Config) (err.List, error) {
+	lex := lexer.New(source, file)
+	lex.SetKeepTrivia(true)
+	ts, e := lex.Tokenize()
+	if e != nil {
+		return nil, e
+	}
+
+	prs := parser.New(ts, false)
+	var errs err.List
+	for _, d := range err.Diagnostics(prs.Check()) {
+		if d.Code != err.UnknownName {
+			errs = append(errs, d)
+		}
+	}
+	if e := errs.Err(); e != nil {
+		return nil, e
+	}
+
+	p := &pass{ts: ts, depths: prs.Depths(), defs: analysis.Definitions(ts)}
+	for _, r := range Rules {
+		if config.enabled(r) {
+			p.rule = r
+			r.check(p)
+		}
+	}
+
+	ignored := ignores(ts, lex.Trivia())
+	var warnings err.List
+	for _, d := range p.warnings {
+		if !ignored.has(d.Span.StartLine, d.Code) {
+			warnings = append(warnings, d)
+		}
+	}
+	warnings.Sort()
+	return warnings, nil
+}
+
+// ignoreList holds the rules turned off on each line.
+type ignoreList map[int][]*Rule
+
+func (l ignoreList) has(line int, code err.Code) bool {
+	return slices.ContainsFunc(l[line], func(r *Rule) bool { return r.Code == code })
+}
+
+// ignores reads the '# lint:ignore RULE[,RULE...]' comments of a program.
+// A comment after code turns the rules off for its own line; a comment on
+// a line of its own, for the next line.
+func ignores(ts []tokens.Token, trivia []tokens.Trivia) ignoreList {
+	code := map[int]bool{}
+	for _, token := range ts {
+		if token.Type != tokens.EOF {
+			code[token.Loc.Line] = true
+		}
+	}
+
+	list := ignoreList{}
+	for _, t := range trivia {
+		if t.Kind != tokens.LineComment {
+			continue
+		}
+		rest, ok := strings.CutPrefix(strings.TrimSpace(strings.TrimPrefix(t.Text, "#")), "lint:ignore")
+		if !ok {
+			continue
+		}
+
+		line := t.Loc.Line
+		if !code[line] {
+			line++
+		}
+		for _, name := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if r, ok := Find(name); ok {
+				list[line] = append(list[line], r)
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,125 @@
+package lint_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/lint"
+)
+
+// warnings lints source with config and returns each warning as
+// "line:col code".
+func warnings(t *testing.T, source string, config lint.Config) []string {
+	t.Helper()
+	list, e := lint.Lint("t.brz", source, config)
+	if e != nil {
+		t.Fatalf("Lint: %v", e)
+	}
+	var got []string
+	for _, d := range list {
+		got = append(got, fmt.Sprintf("%d:%d %s", d.Span.StartLine, d.Span.StartCol, d.Code))
+	}
+	return got
+}
+
+func TestRules(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"unused-define", "define f 1 end", []string{"1:8 L101"}},
+		{"used define", "define f 1 end f", nil},
+		{"define used only by itself", "define f f end", []string{"1:8 L101"}},
+
+		{"shadow-builtin", "define read_line 1 end read_line", []string{"1:8 L102"}},
+		{"shadow-builtin by case", "define DUP 1 end DUP", []string{"1:8 L102"}},
+		{"own name", "define read_it 1 end read_it", nil},
+
+		{"constant-condition", "if 1 do 2 writeln end", []string{"1:1 L103"}},
+		{"constant elif", "if dup do 1 writeln elif true do 2 writeln end", []string{"1:21 L103"}},
+		{"computed condition", "if 1 2 < do 3 writeln end", nil},
+
+		{"unreachable-code", "for true do 1 pop end\n2", []string{"1:1 L103", "2:1 L104"}},
+		{"loop that exits", "for true do 1 exit end\n2", []string{"1:1 L103"}},
+		{"loop that may end", "for dup 0 > do 1 - end 2", nil},
+
+		{"dup-pop", "1 dup pop", []string{"1:3 L105"}},
+		{"dup-pop with code between", "1 dup 2 3 + pop pop", []string{"1:3 L105"}},
+		{"dup used", "1 dup 2 + pop", nil},
+
+		{"unbalanced-branch", "if dup do 1 end", []string{"1:1 L106"}},
+		{"unbalanced else", "if dup do 1 else 2 3 end", []string{"1:1 L106"}},
+		{"balanced branches", "if dup do 1 else 2 end", nil},
+
+		{"redefined-word", "define f 1 end\ndefine f 2 end\nf", []string{"2:8 L107"}},
+		{"different words", "define f 1 end\ndefine g 2 end\nf g", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := warnings(t, c.source, lint.Config{}); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("warnings = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"after code", "1 dup pop # lint:ignore dup-pop", nil},
+		{"by code", "1 dup pop # lint:ignore L105", nil},
+		{"line above", "# lint:ignore dup-pop\n1 dup pop", nil},
+		{"several rules", "if 1 do 2 dup pop writeln end # lint:ignore L103, dup-pop", nil},
+		{"other rule", "1 dup pop # lint:ignore L101", []string{"1:3 L105"}},
+		{"other line", "# lint:ignore dup-pop\n\n1 dup pop", []string{"3:3 L105"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := warnings(t, c.source, lint.Config{}); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("warnings = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if e := os.WriteFile(path, []byte(content), 0o644); e != nil {
+			t.Fatal(e)
+		}
+		return path
+	}
+
+	config, e := lint.LoadConfig(write("ok.json", `{"disable": ["dup-pop", "L101"]}`))
+	if e != nil {
+		t.Fatalf("LoadConfig: %v", e)
+	}
+	source := "define f 1 end\n1 dup pop\nif 1 do 2 writeln end"
+	if got, want := warnings(t, source, config), []string{"3:1 L103"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	for content, want := range map[string]string{
+		`{"disable": ["no-such-rule"]}`: "unknown lint rule 'no-such-rule'",
+		`{"disable": `:                  "bad.json",
+	} {
+		if _, e := lint.LoadConfig(write("bad.json", content)); e == nil || !strings.Contains(e.Error(), want) {
+			t.Errorf("LoadConfig(%s) = %v, want an error mentioning %q", content, e, want)
+		}
+	}
+	if _, e := lint.LoadConfig(filepath.Join(dir, "missing.json")); e == nil {
+		t.Error("LoadConfig of a missing file succeeded")
+	}
+}
@@ -0,0 +1,315 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/analysis"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+func unusedDefine(p *pass) {
+	for i, def := range p.defs {
+		// A later definition of the same name is the one that counts.
+		if _, ok := analysis.Lookup(p.defs[i+1:], def.Name); ok {
+			continue
+		}
+
+		used := false
+		for _, ref := range analysis.References(p.ts, def.Name) {
+			if ref.Loc != def.Ident.Loc && !inside(ref, def) && !isName(p.defs, ref) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			p.warn(def.Ident, "The word '%s' is defined but never used.", def.Name)
+		}
+	}
+}
+
+// inside reports whether token is in the body of def.
+func inside(token tokens.Token, def analysis.Definition) bool {
+	return !before(token.Loc, def.Define.Loc) && before(token.Loc, def.End.Loc)
+}
+
+// isName reports whether token is the name after a 'define'.
+func isName(defs []analysis.Definition, token tokens.Token) bool {
+	for _, def := range defs {
+		if def.Ident.Loc == token.Loc {
+			return true
+		}
+	}
+	return false
+}
+
+func before(a, b tokens.Loc) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+}
+
+func shadowBuiltin(p *pass) {
+	for _, def := range p.defs {
+		name := strings.ToLower(strings.ReplaceAll(def.Name, "_", "-"))
+		if _, ok := analysis.Builtins[name]; ok || name == "true" || name == "false" {
+			p.warn(def.Ident, "The word '%s' is easily mistaken for the built-in '%s'.", def.Name, name).
+				Suggest("give it a name of its own")
+		}
+	}
+}
+
+// condition returns the tokens between the 'if', 'elif' or 'for' at ts[i]
+// and its 'do', and the index of the 'do'.
+func (p *pass) condition(i int) ([]tokens.Token, int, bool) {
+	depth := p.depths[i]
+	if p.ts[i].Type != tokens.Elif {
+		depth++
+	}
+	for j := i + 1; j < len(p.ts); j++ {
+		if p.ts[j].Type == tokens.Do && p.depths[j] == depth {
+			return p.ts[i+1 : j], j, true
+		}
+	}
+	return nil, 0, false
+}
+
+// constant reports whether cond is made only of literals.
+func constant(cond []tokens.Token) bool {
+	if len(cond) == 0 {
+		return false
+	}
+	for _, t := range cond {
+		switch t.Type {
+		case tokens.Int, tokens.Float, tokens.String, tokens.Bool, tokens.Nil:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func constantCondition(p *pass) {
+	for i, token := range p.ts {
+		switch token.Type {
+		case tokens.If, tokens.Elif, tokens.For:
+		default:
+			continue
+		}
+
+		cond, _, ok := p.condition(i)
+		if ok && constant(cond) {
+			p.warn(token, "The condition of this '%s' is always the same.", token.Literal).
+				Note("it is made only of literals")
+		}
+	}
+}
+
+// truthy reports whether a literal is certainly true as a condition.
+func truthy(t tokens.Token) bool {
+	switch v := t.Literal.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return t.Type == tokens.String && v != ""
+	}
+	return false
+}
+
+// end returns the index of the 'end' closing the block opened at ts[i].
+func (p *pass) end(i int) (int, bool) {
+	for j := i + 1; j < len(p.ts); j++ {
+		if p.ts[j].Type == tokens.End && p.depths[j] == p.depths[i] {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// mayExit reports whether running ts may call 'exit', looking into the
+// words it calls. Unknown words may.
+func (p *pass) mayExit(ts []tokens.Token, seen map[string]bool) bool {
+	for _, t := range ts {
+		switch t.Type {
+		case tokens.Exit:
+			return true
+		case tokens.Identifier:
+			name := t.Literal.(string)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			def, ok := analysis.Lookup(p.defs, name)
+			if !ok || p.mayExit(def.Body, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unreachableCode(p *pass) {
+	for i, token := range p.ts {
+		if token.Type != tokens.For {
+			continue
+		}
+
+		cond, do, ok := p.condition(i)
+		if !ok || !constant(cond) || !truthy(cond[len(cond)-1]) {
+			continue
+		}
+		end, ok := p.end(i)
+		if !ok || p.mayExit(p.ts[do+1:end], map[string]bool{}) {
+			continue
+		}
+
+		next := p.ts[end+1]
+		switch next.Type {
+		case tokens.EOF, tokens.End, tokens.Elif, tokens.Else:
+			continue
+		}
+		p.warn(next, "This code never runs.").
+			Note("the loop at %d:%d never ends: its condition is always true and it never calls 'exit'", token.Loc.Line, token.Loc.Col)
+	}
+}
+
+// blockKeyword reports whether t opens, splits or closes a block.
+func blockKeyword(t tokens.Token) bool {
+	switch t.Type {
+	case tokens.If, tokens.Elif, tokens.Else, tokens.Do, tokens.For, tokens.End,
+		tokens.Define, tokens.Test, tokens.AssertStack, tokens.AssertThrows, tokens.EOF:
+		return true
+	}
+	return false
+}
+
+func dupPop(p *pass) {
+	for i, token := range p.ts {
+		if token.Type != tokens.Dup {
+			continue
+		}
+
+		for j := i + 1; j < len(p.ts) && !blockKeyword(p.ts[j]); j++ {
+			if p.ts[j].Type != tokens.Pop {
+				continue
+			}
+			effect, ok := analysis.Infer(p.ts[i+1:j], p.defs)
+			if !ok || effect.In > 0 {
+				break
+			}
+			if effect.Out == 0 {
+				pop := p.ts[j]
+				if j == i+1 {
+					p.warn(token, "'dup' followed by 'pop' does nothing.")
+				} else {
+					p.warn(token, "The value copied by this 'dup' is dropped by the 'pop' at %d:%d without being used.", pop.Loc.Line, pop.Loc.Col)
+				}
+				break
+			}
+		}
+	}
+}
+
+// net returns how much ts changes the stack depth.
+func (p *pass) net(ts []tokens.Token) (int, bool) {
+	effect, ok := analysis.Infer(ts, p.defs)
+	return effect.Out - effect.In, ok
+}
+
+func unbalancedBranch(p *pass) {
+	for i, token := range p.ts {
+		if token.Type != tokens.If {
+			continue
+		}
+
+		type path struct {
+			label string
+			net   int
+		}
+		var paths []path
+
+		// conds is the change made by the conditions that ran so far, each
+		// value taken by its 'do' included.
+		conds := 0
+		keyword, at := token, i
+		ok := true
+
+		for ok && keyword.Type != tokens.End {
+			var body []tokens.Token
+			label := fmt.Sprintf("the '%s' branch at %d:%d", keyword.Literal, keyword.Loc.Line, keyword.Loc.Col)
+
+			start := at + 1
+			if keyword.Type != tokens.Else {
+				var cond []tokens.Token
+				var do int
+				if cond, do, ok = p.condition(at); !ok {
+					break
+				}
+				n, inferred := p.net(cond)
+				ok = inferred
+				conds += n - 1
+				start = do + 1
+			}
+
+			// The branch runs until the next 'elif', 'else' or 'end' of
+			// this block.
+			next := start
+			for ; next < len(p.ts); next++ {
+				t := p.ts[next]
+				if t.Type == tokens.End && p.depths[next] == p.depths[i] ||
+					(t.Type == tokens.Elif || t.Type == tokens.Else) && p.depths[next] == p.depths[i]+1 {
+					break
+				}
+			}
+			if next >= len(p.ts) {
+				ok = false
+				break
+			}
+			body = p.ts[start:next]
+
+			n, inferred := p.net(body)
+			ok = ok && inferred
+			paths = append(paths, path{label, conds + n})
+
+			if keyword.Type == tokens.Else || p.ts[next].Type == tokens.End {
+				if keyword.Type != tokens.Else {
+					paths = append(paths, path{"running no branch", conds})
+				}
+				break
+			}
+			keyword, at = p.ts[next], next
+		}
+
+		if !ok || len(paths) < 2 {
+			continue
+		}
+		balanced := true
+		for _, path := range paths[1:] {
+			balanced = balanced && path.net == paths[0].net
+		}
+		if balanced {
+			continue
+		}
+
+		d := p.warn(token, "The branches of this 'if' leave different stack depths.")
+		for _, path := range paths {
+			d.Note("%s changes the depth by %+d", path.label, path.net)
+		}
+	}
+}
+
+func redefinedWord(p *pass) {
+	first := map[string]analysis.Definition{}
+	for _, def := range p.defs {
+		earlier, ok := first[def.Name]
+		if !ok {
+			first[def.Name] = def
+			continue
+		}
+		p.warn(def.Ident, "The word '%s' is defined again.", def.Name).
+			Note("it was first defined at %d:%d; only the last definition is used", earlier.Ident.Loc.Line, earlier.Ident.Loc.Col)
+	}
+}
@@ -0,0 +1,334 @@
+package lsp
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/analysis"
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// document is an open file, analysed each time it changes.
+type document struct {
+	uri   string
+	lines []string
+
+	tokens      []tokens.Token
+	defs        []analysis.Definition
+	diagnostics err.List
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, lines: strings.Split(text, "\n")}
+
+	ts, lexErr := lexer.New(text, path(uri)).Tokenize()
+	d.tokens = ts
+	d.defs = analysis.Definitions(ts)
+
+	// The parser changes the tokens it checks, so it gets a copy.
+	checked := append([]tokens.Token(nil), ts...)
+	d.diagnostics = append(err.Diagnostics(lexErr), err.Diagnostics(parser.New(checked, false).Check())...)
+	d.diagnostics.Sort()
+
+	return d
+}
+
+// path returns the file a URI names, for the locations of the tokens.
+func path(uri string) string {
+	u, e := url.Parse(uri)
+	if e != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+// line returns line n, counted from 1, without its line ending.
+func (d *document) line(n int) string {
+	if n < 1 || n > len(d.lines) {
+		return ""
+	}
+	return strings.TrimSuffix(d.lines[n-1], "\r")
+}
+
+// position converts loc, whose columns count runes, to a position, whose
+// characters count UTF-16 code units.
+func (d *document) position(loc tokens.Loc) position {
+	runes := []rune(d.line(loc.Line))
+	col := min(max(loc.Col-1, 0), len(runes))
+	return position{Line: loc.Line - 1, Character: len(utf16.Encode(runes[:col]))}
+}
+
+// loc converts a position back to a location in the document.
+func (d *document) loc(pos position) tokens.Loc {
+	units := 0
+	col := 1
+	for _, r := range d.line(pos.Line + 1) {
+		if units >= pos.Character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		col++
+	}
+	return tokens.Loc{Line: pos.Line + 1, Col: col}
+}
+
+func (d *document) tokenRange(token tokens.Token) textRange {
+	return d.spanRange(err.TokenSpan(token))
+}
+
+func (d *document) spanRange(span err.Span) textRange {
+	return textRange{
+		Start: d.position(tokens.Loc{Line: span.StartLine, Col: span.StartCol}),
+		End:   d.position(tokens.Loc{Line: span.EndLine, Col: span.EndCol}),
+	}
+}
+
+// definitionRange returns the range from 'define' to the end of its 'end'.
+func (d *document) definitionRange(def analysis.Definition) textRange {
+	return textRange{
+		Start: d.position(def.Define.Loc),
+		End:   d.tokenRange(def.End).End,
+	}
+}
+
+// source returns the text of r.
+func (d *document) source(r textRange) string {
+	var b strings.Builder
+	for n := r.Start.Line; n <= r.End.Line; n++ {
+		units := utf16.Encode([]rune(d.line(n + 1)))
+		from, to := 0, len(units)
+		if n == r.Start.Line {
+			from = min(r.Start.Character, to)
+		}
+		if n == r.End.Line {
+			to = min(r.End.Character, to)
+		}
+		if n > r.Start.Line {
+			b.WriteString("\n")
+		}
+		b.WriteString(string(utf16.Decode(units[from:max(from, to)])))
+	}
+	return b.String()
+}
+
+// at returns the token under pos.
+func (d *document) at(pos position) (tokens.Token, bool) {
+	return analysis.At(d.tokens, d.loc(pos))
+}
+
+// word returns the name of the defined word under pos.
+func (d *document) word(pos position) (string, bool) {
+	token, ok := d.at(pos)
+	if !ok || token.Type != tokens.Identifier {
+		return "", false
+	}
+	return token.Literal.(string), true
+}
+
+// isDeclaration reports whether token is the name after a 'define'.
+func (d *document) isDeclaration(token tokens.Token) bool {
+	for _, def := range d.defs {
+		if def.Ident.Loc == token.Loc {
+			return true
+		}
+	}
+	return false
+}
+
+// effect returns the stack effect of a defined word, if it can be known.
+func (d *document) effect(def analysis.Definition) (analysis.Effect, bool) {
+	return analysis.Infer(def.Body, d.defs)
+}
+
+// comment returns the '#' comment lines written right above def, without
+// the '#'. The document is lexed without trivia, so they are read from the
+// text.
+func (d *document) comment(def analysis.Definition) string {
+	prefix := []rune(d.line(def.Define.Loc.Line))
+	if strings.TrimSpace(string(prefix[:min(def.Define.Loc.Col-1, len(prefix))])) != "" {
+		return ""
+	}
+
+	var lines []string
+	for n := def.Define.Loc.Line - 1; n >= 1; n-- {
+		line := strings.TrimSpace(d.line(n))
+		if !strings.HasPrefix(line, "#") || strings.HasPrefix(line, "#[") {
+			break
+		}
+		lines = append([]string{strings.TrimSpace(strings.TrimPrefix(line, "#"))}, lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hover describes the word under pos.
+func (d *document) hover(pos position) (*hover, bool) {
+	token, ok := d.at(pos)
+	if !ok {
+		return nil, false
+	}
+
+	var b strings.Builder
+	switch name, _ := analysis.Name(token); {
+	case token.Type == tokens.Identifier:
+		def, ok := analysis.Lookup(d.defs, name)
+		if !ok {
+			return nil, false
+		}
+
+		fmt.Fprintf(&b, "**%s**", name)
+		if effect, ok := d.effect(def); ok {
+			fmt.Fprintf(&b, " `%s`", effect)
+		}
+		if comment := d.comment(def); comment != "" {
+			fmt.Fprintf(&b, "\n\n%s", comment)
+		}
+		fmt.Fprintf(&b, "\n\n```beremiz\n%s\n```", d.source(d.definitionRange(def)))
+
+	default:
+		builtin, ok := analysis.Builtins[name]
+		if !ok {
+			return nil, false
+		}
+		fmt.Fprintf(&b, "**%s**", name)
+		if builtin.Effect != "" {
+			fmt.Fprintf(&b, " `%s`", builtin.Effect)
+		}
+		fmt.Fprintf(&b, "\n\n%s", builtin.Doc)
+	}
+
+	return &hover{
+		Contents: markup{Kind: "markdown", Value: b.String()},
+		Range:    d.tokenRange(token),
+	}, true
+}
+
+// definition returns where the word under pos is defined.
+func (d *document) definition(pos position) ([]location, bool) {
+	name, ok := d.word(pos)
+	if !ok {
+		return nil, false
+	}
+	def, ok := analysis.Lookup(d.defs, name)
+	if !ok {
+		return nil, false
+	}
+	return []location{{URI: d.uri, Range: d.tokenRange(def.Ident)}}, true
+}
+
+// references returns every use of the word under pos.
+func (d *document) references(pos position, declarations bool) []location {
+	name, ok := d.word(pos)
+	if !ok {
+		return nil
+	}
+
+	locs := []location{}
+	for _, token := range analysis.References(d.tokens, name) {
+		if !declarations && d.isDeclaration(token) {
+			continue
+		}
+		locs = append(locs, location{URI: d.uri, Range: d.tokenRange(token)})
+	}
+	return locs
+}
+
+// completions lists the keywords, operators and defined words.
+func (d *document) completions() []completionItem {
+	items := []completionItem{
+		{Label: "true", Kind: completionConstant, Detail: "-> bool"},
+		{Label: "false", Kind: completionConstant, Detail: "-> bool"},
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(analysis.Builtins)) {
+		builtin := analysis.Builtins[name]
+		kind := completionKeyword
+		if _, ok := tokens.Keywords[name]; !ok {
+			kind = completionOperator
+		}
+		items = append(items, completionItem{
+			Label:         name,
+			Kind:          kind,
+			Detail:        builtin.Effect,
+			Documentation: &markup{Kind: "markdown", Value: builtin.Doc},
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, def := range d.defs {
+		if seen[def.Name] {
+			continue
+		}
+		seen[def.Name] = true
+
+		item := completionItem{Label: def.Name, Kind: completionFunction}
+		if effect, ok := d.effect(def); ok {
+			item.Detail = effect.String()
+		}
+		if comment := d.comment(def); comment != "" {
+			item.Documentation = &markup{Kind: "markdown", Value: comment}
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// symbols lists every 'define', in source order.
+func (d *document) symbols() []documentSymbol {
+	symbols := []documentSymbol{}
+	for _, def := range d.defs {
+		symbol := documentSymbol{
+			Name:           def.Name,
+			Kind:           symbolFunction,
+			Range:          d.definitionRange(def),
+			SelectionRange: d.tokenRange(def.Ident),
+		}
+		if effect, ok := d.effect(def); ok {
+			symbol.Detail = effect.String()
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// publishable converts the diagnostics found in the document.
+func (d *document) publishable() []diagnostic {
+	diagnostics := []diagnostic{}
+	for _, diag := range d.diagnostics {
+		message := diag.Message
+		for _, note := range diag.Notes {
+			message += "\nnote: " + note
+		}
+		for _, suggestion := range diag.Suggestions {
+			message += "\nhelp: " + suggestion
+		}
+
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    d.spanRange(diag.Span),
+			Severity: severity(diag.Severity),
+			Code:     string(diag.Code),
+			Source:   "beremiz",
+			Message:  message,
+		})
+	}
+	return diagnostics
+}
+
+// severity maps a diagnostic severity to the protocol's numbers.
+func severity(s err.Severity) int {
+	switch s {
+	case err.SeverityWarning:
+		return 2
+	case err.SeverityNote:
+		return 3
+	default:
+		return 1
+	}
+}
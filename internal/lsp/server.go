@@ -0,0 +1,202 @@
+// Package lsp serves the Language Server Protocol, so editors can show a
+// Beremiz program's errors as it is written and navigate its words. It only
+// reads programs: nothing is ever run.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/wire"
+)
+
+// Server is a language server talking to one client.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// New returns a Server reading messages from in and writing to out.
+func New(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends exit or in ends. Exiting
+// without a shutdown request first is an error, as the protocol says.
+func (s *Server) Serve() error {
+	for {
+		body, e := wire.Read(s.in)
+		if errors.Is(e, io.EOF) {
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+
+		var msg message
+		if e := json.Unmarshal(body, &msg); e != nil {
+			return fmt.Errorf("invalid message: %v", e)
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit without shutdown")
+			}
+			return nil
+		}
+
+		s.handle(msg)
+	}
+}
+
+func (s *Server) respond(msg message, result any) {
+	wire.Write(s.out, response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *Server) fail(msg message, code int, format string, args ...any) {
+	wire.Write(s.out, errorResponse{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Error:   responseError{Code: code, Message: fmt.Sprintf(format, args...)},
+	})
+}
+
+func (s *Server) notify(method string, params any) {
+	wire.Write(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// handle answers one request or notification.
+func (s *Server) handle(msg message) {
+	isRequest := msg.ID != nil
+
+	if !s.initialized && msg.Method != "initialize" {
+		if isRequest {
+			s.fail(msg, serverNotInitialized, "The server is not initialized.")
+		}
+		return
+	}
+
+	switch msg.Method {
+	case "initialize":
+		s.initialized = true
+		s.respond(msg, map[string]any{
+			"capabilities": map[string]any{
+				// The whole text is sent on every change.
+				"textDocumentSync":       map[string]any{"openClose": true, "change": 1},
+				"hoverProvider":          true,
+				"definitionProvider":     true,
+				"referencesProvider":     true,
+				"documentSymbolProvider": true,
+				"completionProvider":     map[string]any{},
+			},
+			"serverInfo": map[string]any{"name": "beremiz"},
+		})
+
+	case "shutdown":
+		s.shutdown = true
+		s.respond(msg, nil)
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument textDocument `json:"textDocument"`
+		}
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+
+	case "textDocument/didChange":
+		var params changeParams
+		if json.Unmarshal(msg.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			last := params.ContentChanges[len(params.ContentChanges)-1]
+			s.update(params.TextDocument.URI, last.Text)
+		}
+
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument textDocument `json:"textDocument"`
+		}
+		if json.Unmarshal(msg.Params, &params) == nil {
+			delete(s.docs, params.TextDocument.URI)
+			s.publish(params.TextDocument.URI, []diagnostic{})
+		}
+
+	case "textDocument/hover":
+		s.withDocument(msg, func(d *document, params positionParams) any {
+			if h, ok := d.hover(params.Position); ok {
+				return h
+			}
+			return nil
+		})
+
+	case "textDocument/definition":
+		s.withDocument(msg, func(d *document, params positionParams) any {
+			if locs, ok := d.definition(params.Position); ok {
+				return locs
+			}
+			return nil
+		})
+
+	case "textDocument/references":
+		s.withDocument(msg, func(d *document, params positionParams) any {
+			return d.references(params.Position, params.Context.IncludeDeclaration)
+		})
+
+	case "textDocument/completion":
+		s.withDocument(msg, func(d *document, params positionParams) any {
+			return d.completions()
+		})
+
+	case "textDocument/documentSymbol":
+		s.withDocument(msg, func(d *document, params positionParams) any {
+			return d.symbols()
+		})
+
+	default:
+		if isRequest {
+			s.fail(msg, methodNotFound, "Unsupported method '%s'.", msg.Method)
+		}
+	}
+}
+
+// withDocument answers a request about an open document with what answer
+// returns.
+func (s *Server) withDocument(msg message, answer func(*document, positionParams) any) {
+	var params positionParams
+	if e := json.Unmarshal(msg.Params, &params); e != nil {
+		s.fail(msg, invalidParams, "Invalid parameters: %v.", e)
+		return
+	}
+
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		s.fail(msg, invalidParams, "Document '%s' is not open.", params.TextDocument.URI)
+		return
+	}
+	s.respond(msg, answer(d, params))
+}
+
+// update analyses the new text of a document and publishes its
+// diagnostics.
+func (s *Server) update(uri, text string) {
+	d := newDocument(uri, text)
+	s.docs[uri] = d
+	s.publish(uri, d.publishable())
+}
+
+func (s *Server) publish(uri string, diagnostics []diagnostic) {
+	s.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
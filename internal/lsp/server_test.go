@@ -0,0 +1,213 @@
+package lsp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/lsp"
+	"github.com/adaiasmagdiel/beremiz-go/internal/wire"
+)
+
+const uri = "file:///work/square.brz"
+
+// client drives a Server over pipes, the way an editor would.
+type client struct {
+	t    *testing.T
+	in   io.WriteCloser
+	msgs chan map[string]any
+	done chan error
+	id   int
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	c := &client{t: t, in: inW, msgs: make(chan map[string]any, 64), done: make(chan error, 1)}
+	go func() {
+		c.done <- lsp.New(inR, outW).Serve()
+		outW.Close()
+	}()
+	go func() {
+		r := bufio.NewReader(outR)
+		for {
+			body, e := wire.Read(r)
+			if e != nil {
+				close(c.msgs)
+				return
+			}
+			var msg map[string]any
+			json.Unmarshal(body, &msg)
+			c.msgs <- msg
+		}
+	}()
+
+	t.Cleanup(func() { inW.Close() })
+	return c
+}
+
+// next returns the next message from the server.
+func (c *client) next() map[string]any {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.msgs:
+		if !ok {
+			c.t.Fatal("the server stopped")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for the server")
+	}
+	return nil
+}
+
+// request sends a request and returns its response.
+func (c *client) request(method string, params any) map[string]any {
+	c.t.Helper()
+	c.id++
+	wire.Write(c.in, map[string]any{"jsonrpc": "2.0", "id": c.id, "method": method, "params": params})
+
+	msg := c.next()
+	if msg["id"] != float64(c.id) {
+		c.t.Fatalf("%s: got %v, want the response to request %d", method, msg, c.id)
+	}
+	return msg
+}
+
+// result sends a request and returns the result of its response.
+func (c *client) result(method string, params any) any {
+	c.t.Helper()
+	msg := c.request(method, params)
+	if msg["error"] != nil {
+		c.t.Fatalf("%s failed: %v", method, msg["error"])
+	}
+	return msg["result"]
+}
+
+func (c *client) notify(method string, params any) {
+	wire.Write(c.in, map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// diagnostics returns the next diagnostics published for uri.
+func (c *client) diagnostics() []any {
+	c.t.Helper()
+	msg := c.next()
+	params, _ := msg["params"].(map[string]any)
+	if msg["method"] != "textDocument/publishDiagnostics" || params["uri"] != uri {
+		c.t.Fatalf("got %v, want diagnostics for %s", msg, uri)
+	}
+	return params["diagnostics"].([]any)
+}
+
+func at(line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+// start returns the start of the range of v, as line and character.
+func start(v any) (any, any) {
+	r := v.(map[string]any)["range"].(map[string]any)["start"].(map[string]any)
+	return r["line"], r["character"]
+}
+
+func TestSession(t *testing.T) {
+	c := newClient(t)
+
+	if msg := c.request("textDocument/hover", at(0, 0)); msg["error"] == nil {
+		t.Errorf("hover before initialize = %v, want an error", msg)
+	}
+
+	caps := c.result("initialize", map[string]any{"capabilities": map[string]any{}}).(map[string]any)["capabilities"].(map[string]any)
+	for _, provider := range []string{"hoverProvider", "definitionProvider", "referencesProvider"} {
+		if caps[provider] != true {
+			t.Errorf("capabilities[%s] = %v, want true", provider, caps[provider])
+		}
+	}
+	c.notify("initialized", map[string]any{})
+
+	text := "# Squares a number.\ndefine square\n    dup *\nend\n3 square squre\n"
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "beremiz", "version": 1, "text": text},
+	})
+
+	diags := c.diagnostics()
+	if len(diags) != 1 {
+		t.Fatalf("diagnostics = %v, want 1", diags)
+	}
+	diag := diags[0].(map[string]any)
+	if line, char := start(diag); diag["code"] != "E204" || line != float64(4) || char != float64(9) {
+		t.Errorf("diagnostic = %v, want E204 at 4:9", diag)
+	}
+	if msg := diag["message"].(string); !strings.Contains(msg, "did you mean 'square'?") {
+		t.Errorf("message = %q, want a suggestion of 'square'", msg)
+	}
+
+	hover := c.result("textDocument/hover", at(4, 4)).(map[string]any)
+	value := hover["contents"].(map[string]any)["value"].(string)
+	for _, want := range []string{"**square**", "Squares a number.", "dup *"} {
+		if !strings.Contains(value, want) {
+			t.Errorf("hover = %q, want it to contain %q", value, want)
+		}
+	}
+	hover = c.result("textDocument/hover", at(2, 5)).(map[string]any)
+	if value := hover["contents"].(map[string]any)["value"].(string); !strings.HasPrefix(value, "**dup**") {
+		t.Errorf("hover on dup = %q, want the built-in's docs", value)
+	}
+	if hover := c.result("textDocument/hover", at(4, 0)); hover != nil {
+		t.Errorf("hover on a number = %v, want nothing", hover)
+	}
+
+	locs := c.result("textDocument/definition", at(4, 4)).([]any)
+	if len(locs) != 1 {
+		t.Fatalf("definition = %v, want 1 location", locs)
+	}
+	if line, char := start(locs[0]); locs[0].(map[string]any)["uri"] != uri || line != float64(1) || char != float64(7) {
+		t.Errorf("definition = %v, want %s at 1:7", locs[0], uri)
+	}
+
+	found := map[string]float64{}
+	for _, item := range c.result("textDocument/completion", at(4, 0)).([]any) {
+		item := item.(map[string]any)
+		found[item["label"].(string)] = item["kind"].(float64)
+	}
+	for label, kind := range map[string]float64{"square": 3, "dup": 14, "true": 21} {
+		if found[label] != kind {
+			t.Errorf("completion %q has kind %v, want %v", label, found[label], kind)
+		}
+	}
+
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{"text": strings.Replace(text, "squre", "square", 1)}},
+	})
+	if diags := c.diagnostics(); len(diags) != 0 {
+		t.Errorf("diagnostics after the fix = %v, want none", diags)
+	}
+
+	c.result("shutdown", nil)
+	c.notify("exit", nil)
+	select {
+	case e := <-c.done:
+		if e != nil {
+			t.Errorf("Serve: %v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Serve didn't return after exit")
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	c := newClient(t)
+	c.result("initialize", map[string]any{})
+	c.notify("exit", nil)
+
+	if e := <-c.done; e == nil {
+		t.Error("Serve returned no error for exit without shutdown")
+	}
+}
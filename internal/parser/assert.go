@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// throwsBlock is an 'assert-throws' block being run. An error raised
+// inside it is caught and the program goes on after its 'end'.
+type throwsBlock struct {
+	token tokens.Token
+	// end is the index of the 'end' closing the block.
+	end int
+	// stack, frames and inLoop are put back as they were when the block
+	// began once an error is caught.
+	stack  []tokens.Token
+	frames int
+	inLoop int
+}
+
+// evalAssert runs one of the assertion words (assert, assert-eq,
+// assert-stack) against the stack.
+func (p *Parser) evalAssert(token tokens.Token, stack []tokens.Token) ([]tokens.Token, error) {
+	switch token.Type {
+	case tokens.Assert:
+		var value tokens.Token
+		var e error
+
+		stack, value, e = Pop(stack)
+		if e != nil {
+			return stack, p.fail(token, err.StackUnderflow, fmt.Sprintf("The keyword '%s' requires value in stack. Stack is empty.", token.Literal))
+		}
+		if !toBool(value) {
+			return stack, p.fail(token, err.AssertionFailed, "Assertion failed: the value is not true.").
+				Note("found: %s", describe(value))
+		}
+
+	case tokens.AssertEq:
+		if len(stack) < 2 {
+			return stack, p.fail(token, err.StackUnderflow, fmt.Sprintf(
+				"The '%s' keyword requires two operands in stack. Found %d.",
+				token.Literal, len(stack)))
+		}
+
+		actual := stack[len(stack)-2]
+		expected := stack[len(stack)-1]
+		stack = stack[:len(stack)-2]
+
+		if !same(actual, expected) {
+			return stack, p.fail(token, err.AssertionFailed, "Assertion failed: the values are not equal.").
+				Note("expected: %s", describe(expected)).
+				Note("   found: %s", describe(actual))
+		}
+
+	case tokens.AssertStack:
+		expected := p.Tokens[p.pos+1 : token.JmpTo-1]
+		if !slices.EqualFunc(stack, expected, same) {
+			return stack, p.fail(token, err.AssertionFailed, "Assertion failed: the stack doesn't hold the expected values.").
+				Note("expected: %s", describeAll(expected)).
+				Note("   found: %s", describeAll(stack))
+		}
+	}
+
+	return stack, nil
+}
+
+// catch reports whether e was raised inside an 'assert-throws' block, as
+// the block expects. If so, the program is set to go on after the block.
+// Running out of a limit and leaving through exit are never caught.
+func (p *Parser) catch(e error) bool {
+	if e == nil || len(p.throws) == 0 || p.exited {
+		return false
+	}
+
+	var d *err.Diagnostic
+	if !errors.As(e, &d) || d.Code == err.LimitExceeded {
+		return false
+	}
+	if kind := d.Code.Kind(); kind != "RuntimeError" && kind != "AssertionError" {
+		return false
+	}
+
+	block := p.throws[len(p.throws)-1]
+	p.throws = p.throws[:len(p.throws)-1]
+
+	p.stack = block.stack
+	p.frames = p.frames[:block.frames]
+	p.inLoop = block.inLoop
+	p.pos = block.end + 1
+	return true
+}
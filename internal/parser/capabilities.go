@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Capability names a kind of access to the outside world that a program
+// must be granted before the words that need it can run.
+type Capability string
+
+const (
+	FSRead  Capability = "fs:read"
+	FSWrite Capability = "fs:write"
+	Env     Capability = "env"
+	Process Capability = "process"
+	// Exec, Net and Time aren't needed by any built-in word. They are for
+	// host words that run commands, reach the network or read the clock.
+	Exec Capability = "exec"
+	Net  Capability = "net"
+	Time Capability = "time"
+)
+
+// Capabilities lists every capability known to the interpreter.
+var Capabilities = []Capability{FSRead, FSWrite, Env, Process, Exec, Net, Time}
+
+// requirements maps the built-in words to the capability they need.
+var requirements = map[tokens.TokenType]Capability{
+	tokens.ReadFile:   FSRead,
+	tokens.FileExists: FSRead,
+	tokens.ListDir:    FSRead,
+	tokens.WriteFile:  FSWrite,
+	tokens.AppendFile: FSWrite,
+	tokens.RemoveFile: FSWrite,
+	tokens.Getenv:     Env,
+	tokens.Setenv:     Env,
+	tokens.Args:       Process,
+	tokens.Exit:       Process,
+}
+
+// PermissionError is returned when a word needs a capability that wasn't
+// granted.
+type PermissionError struct {
+	Word       string
+	Capability Capability
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("Permission denied: '%s' requires the '%s' capability.", e.Word, e.Capability)
+}
+
+// SetCapabilities replaces the granted capabilities. Nothing is granted by
+// default.
+func (p *Parser) SetCapabilities(caps ...Capability) {
+	p.caps = make(map[Capability]bool, len(caps))
+	for _, c := range caps {
+		p.caps[c] = true
+	}
+}
+
+// require checks that every capability in caps was granted.
+func (p *Parser) require(word string, caps ...Capability) error {
+	for _, c := range caps {
+		if !p.caps[c] {
+			return &PermissionError{Word: word, Capability: c}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/pathutils"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// popStrings pops n string operands, returning them in push order.
+func (p *Parser) popStrings(token tokens.Token, stack []tokens.Token, n int) ([]tokens.Token, []string, error) {
+	if len(stack) < n {
+		return stack, nil, p.fail(token, err.StackUnderflow, fmt.Sprintf(
+			"The keyword '%s' requires %d value(s) in stack. Found %d.", token.Literal, n, len(stack)))
+	}
+
+	values := make([]string, n)
+	for i, t := range stack[len(stack)-n:] {
+		str, ok := t.Literal.(string)
+		if t.Type != tokens.String || !ok {
+			return stack, nil, p.fail(token, err.TypeMismatch, fmt.Sprintf(
+				"The keyword '%s' expects string operands, but got '%s'.", token.Literal, t.Type))
+		}
+		values[i] = str
+	}
+
+	return stack[:len(stack)-n], values, nil
+}
+
+func fileError(action, path string, e error) error {
+	var pathErr *fs.PathError
+	if errors.As(e, &pathErr) {
+		e = pathErr.Err
+	}
+	return fmt.Errorf("Unable to %s '%s': %v.", action, path, e)
+}
+
+// evalFile runs one of the file words against the stack.
+func (p *Parser) evalFile(token tokens.Token, stack []tokens.Token) ([]tokens.Token, error) {
+	if e := p.require(token.Literal.(string), requirements[token.Type]); e != nil {
+		return stack, e
+	}
+
+	operands := 1
+	if token.Type == tokens.WriteFile || token.Type == tokens.AppendFile {
+		operands = 2
+	}
+
+	stack, args, e := p.popStrings(token, stack, operands)
+	if e != nil {
+		return stack, e
+	}
+
+	name := args[len(args)-1]
+	path, e := pathutils.ResolveFilePath(name)
+	if e != nil {
+		return stack, fmt.Errorf("Unable to resolve path '%s': %v.", name, e)
+	}
+
+	switch token.Type {
+	case tokens.ReadFile:
+		content, e := os.ReadFile(path)
+		if e != nil {
+			return stack, fileError("read file", name, e)
+		}
+		stack = append(stack, tokens.Token{Type: tokens.String, Literal: string(content), Loc: token.Loc})
+
+	case tokens.WriteFile:
+		if e := os.WriteFile(path, []byte(args[0]), 0o644); e != nil {
+			return stack, fileError("write file", name, e)
+		}
+
+	case tokens.AppendFile:
+		file, e := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if e != nil {
+			return stack, fileError("open file", name, e)
+		}
+		defer file.Close()
+
+		if _, e := file.WriteString(args[0]); e != nil {
+			return stack, fileError("append to file", name, e)
+		}
+
+	case tokens.FileExists:
+		_, e := os.Stat(path)
+		stack = append(stack, tokens.Token{Type: tokens.Bool, Literal: e == nil, Loc: token.Loc})
+
+	case tokens.ListDir:
+		entries, e := os.ReadDir(path)
+		if e != nil {
+			return stack, fileError("list directory", name, e)
+		}
+
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			stack = append(stack, tokens.Token{Type: tokens.String, Literal: n, Loc: token.Loc})
+		}
+		stack = append(stack, tokens.Token{Type: tokens.Int, Literal: int64(len(names)), Loc: token.Loc})
+
+	case tokens.RemoveFile:
+		if e := os.Remove(path); e != nil {
+			return stack, fileError("remove", name, e)
+		}
+	}
+
+	return stack, nil
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"context"
+	"slices"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Hook watches a program run, for debuggers and tracers. Before is called
+// before each instruction and After once it ran without error, including the
+// Call and Return markers around defined words. An error from either stops
+// the program and is returned by Eval as it is.
+type Hook interface {
+	Before(s *State) error
+	After(s *State) error
+}
+
+// State is the running program, as a Hook sees it. It is only valid during
+// the call it was passed to.
+type State struct {
+	// Token is the instruction about to run, or that just ran.
+	Token tokens.Token
+	// Stack is the data stack, bottom first. Hooks must not change it.
+	Stack []tokens.Token
+	// Frames lists the active word calls, outermost first.
+	Frames []Frame
+
+	p *Parser
+}
+
+// SetHook makes Eval call h around every instruction. Without a hook, Eval
+// pays a single nil check per instruction.
+func (p *Parser) SetHook(h Hook) {
+	p.hook = h
+}
+
+func (p *Parser) state(token tokens.Token, stack []tokens.Token) *State {
+	return &State{Token: token, Stack: stack, Frames: p.frames, p: p}
+}
+
+// Flush writes out anything the program printed so far.
+func (s *State) Flush() error {
+	return s.p.output.Flush()
+}
+
+// Eval runs source against a copy of the stack, with the words defined by
+// the program, and returns the stack it leaves. The program's own stack is
+// not changed.
+func (s *State) Eval(ctx context.Context, source string) ([]tokens.Token, error) {
+	ts, e := lexer.New(source, "<eval>").Tokenize()
+	if e != nil {
+		return nil, e
+	}
+
+	child := &Parser{
+		Tokens: ts,
+		stack:  slices.Clone(s.Stack),
+		input:  s.p.input,
+		output: s.p.output,
+		errOut: s.p.errOut,
+		caps:   s.p.caps,
+		args:   s.p.args,
+		words:  s.p.words,
+		limits: s.p.limits,
+		defs:   s.p.defs,
+	}
+
+	e = child.Eval(ctx)
+	return child.stack, e
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// SetInput changes where the read-* words take their input from. It
+// defaults to os.Stdin.
+func (p *Parser) SetInput(r io.Reader) {
+	p.input = bufio.NewReader(r)
+}
+
+func nilToken(loc tokens.Loc) tokens.Token {
+	return tokens.Token{Type: tokens.Nil, Literal: "nil", Loc: loc}
+}
+
+// readLine returns the next line of input without its line terminator, or nil
+// at EOF.
+func (p *Parser) readLine(token tokens.Token) (tokens.Token, error) {
+	line, e := p.input.ReadString('\n')
+	if e != nil && !errors.Is(e, io.EOF) {
+		return tokens.Token{}, fmt.Errorf("Unable to read from stdin: %v.", e)
+	}
+
+	if errors.Is(e, io.EOF) && line == "" {
+		return nilToken(token.Loc), nil
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+
+	return tokens.Token{Type: tokens.String, Literal: line, Loc: token.Loc}, nil
+}
+
+func (p *Parser) readAll(token tokens.Token) (tokens.Token, error) {
+	content, e := io.ReadAll(p.input)
+	if e != nil {
+		return tokens.Token{}, fmt.Errorf("Unable to read from stdin: %v.", e)
+	}
+
+	return tokens.Token{Type: tokens.String, Literal: string(content), Loc: token.Loc}, nil
+}
+
+func (p *Parser) readNumber(token tokens.Token) (tokens.Token, error) {
+	line, e := p.readLine(token)
+	if e != nil || line.Type == tokens.Nil {
+		return line, e
+	}
+
+	n, e := lexer.ParseNumber(line.Literal.(string))
+	if e != nil {
+		return tokens.Token{}, fmt.Errorf("Unable to read a number from stdin: %v.", e)
+	}
+	n.Loc = token.Loc
+
+	return n, nil
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+var (
+	ErrStepLimit   = errors.New("instruction limit exceeded")
+	ErrStackLimit  = errors.New("stack size limit exceeded")
+	ErrCallDepth   = errors.New("call depth limit exceeded")
+	ErrStringLimit = errors.New("string size limit exceeded")
+)
+
+// Limits bounds the resources a program may use. Zero means unlimited.
+type Limits struct {
+	// MaxSteps is the maximum number of instructions executed.
+	MaxSteps int
+	// MaxStackDepth is the maximum number of values on the data stack.
+	MaxStackDepth int
+	// MaxCallDepth is the maximum nesting of word calls.
+	MaxCallDepth int
+	// MaxStringLen is the maximum length, in bytes, of a string value.
+	MaxStringLen int
+}
+
+// ctxCheckInterval is how many instructions run between checks of the
+// context, which are too slow to do on every one.
+const ctxCheckInterval = 1024
+
+// Frame is an active call of a defined word.
+type Frame = err.Frame
+
+// SetLimits sets the resource limits checked while evaluating.
+func (p *Parser) SetLimits(limits Limits) {
+	p.limits = limits
+}
+
+// stop is like fail, but keeps cause reachable through errors.Is and
+// errors.As.
+func (p *Parser) stop(token tokens.Token, cause error, message string) error {
+	e := p.fail(token, err.LimitExceeded, message)
+	e.Err = cause
+	return e
+}
+
+// checkLimits runs after each instruction, against the state it left.
+// depth is the size of the stack before the instruction ran.
+func (p *Parser) checkLimits(token tokens.Token, depth int, stack []tokens.Token) error {
+	if max := p.limits.MaxSteps; max > 0 && p.steps > max {
+		return p.stop(token, ErrStepLimit, fmt.Sprintf(
+			"Execution stopped: more than %d instructions were executed.", max))
+	}
+
+	if max := p.limits.MaxStackDepth; max > 0 && len(stack) > max {
+		return p.stop(token, ErrStackLimit, fmt.Sprintf(
+			"Execution stopped: the stack grew past %d values.", max))
+	}
+
+	if max := p.limits.MaxCallDepth; max > 0 && len(p.frames) > max {
+		return p.stop(token, ErrCallDepth, fmt.Sprintf(
+			"Execution stopped: calls nested deeper than %d words.", max))
+	}
+
+	if max := p.limits.MaxStringLen; max > 0 {
+		for _, value := range stack[pushed(token, depth, stack):] {
+			if str, ok := value.Literal.(string); ok && value.Type == tokens.String && len(str) > max {
+				return p.stop(token, ErrStringLimit, fmt.Sprintf(
+					"Execution stopped: a string grew past %d bytes.", max))
+			}
+		}
+	}
+
+	return nil
+}
+
+// pushed returns where the values token may have pushed start on stack,
+// given the depth before it ran. The values below were there before, and
+// already checked.
+func pushed(token tokens.Token, depth int, stack []tokens.Token) int {
+	// A host word can rewrite any part of the stack.
+	if token.Type == tokens.Identifier {
+		return 0
+	}
+	// The built-ins push on top of what they leave of their operands: one
+	// value after popping any number of them, or several, like 'args' and
+	// 'list-dir', after popping at most one.
+	return max(min(depth, len(stack))-1, 0)
+}
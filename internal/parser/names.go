@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// maxSuggestions is how many names an unknown identifier suggests at most.
+const maxSuggestions = 3
+
+// checkNames reports every identifier that is neither defined in the
+// program nor a host word, before anything runs.
+func (p Parser) checkNames(defs map[string][]tokens.Token) err.List {
+	var errs err.List
+
+	for idx, token := range p.Tokens {
+		if token.Type != tokens.Identifier {
+			continue
+		}
+		// The name after 'define' is the one being defined.
+		if idx > 0 && p.Tokens[idx-1].Type == tokens.Define {
+			continue
+		}
+
+		name := token.Literal.(string)
+		if _, ok := defs[name]; ok {
+			continue
+		}
+		if _, ok := p.words[name]; ok {
+			continue
+		}
+
+		errs = append(errs, p.undefined(token, err.UnknownName, defs))
+	}
+
+	return errs
+}
+
+// undefined builds the error for an unknown identifier, suggesting the
+// closest keywords, defined words and host words. code tells whether the
+// name was found by Check or while running.
+func (p Parser) undefined(token tokens.Token, code err.Code, defs map[string][]tokens.Token) *err.Diagnostic {
+	name := token.Literal.(string)
+	d := p.fail(token, code, fmt.Sprintf("Name '%s' is not defined.", name))
+
+	var known []string
+	for keyword := range tokens.Keywords {
+		known = append(known, keyword)
+	}
+	known = append(known, "true", "false")
+	for word := range defs {
+		known = append(known, word)
+	}
+	for word := range p.words {
+		known = append(known, word)
+	}
+
+	for _, match := range closest(name, known) {
+		d.Suggest("did you mean '%s'?", match)
+	}
+	return d
+}
+
+// closest returns the names in known nearest to name, best first. Names
+// further than about a third of name's length away are left out.
+func closest(name string, known []string) []string {
+	limit := max(1, len([]rune(name))/3)
+
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	seen := map[string]bool{}
+
+	for _, candidate := range known {
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+
+		if d := editDistance(name, candidate); d <= limit {
+			matches = append(matches, match{candidate, d})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		names = append(names, matches[i].name)
+	}
+	return names
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent runes needed to turn a into b, so "swpa" is one edit from "swap".
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(s)][len(t)]
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// SetArgs sets the command-line arguments pushed by the args word.
+func (p *Parser) SetArgs(args []string) {
+	p.args = args
+}
+
+// Exited reports whether the program stopped through the exit word, and
+// with which status code.
+func (p *Parser) Exited() (bool, int) {
+	return p.exited, p.exitCode
+}
+
+// evalOS runs one of the process words (args, getenv, setenv, exit)
+// against the stack.
+func (p *Parser) evalOS(token tokens.Token, stack []tokens.Token) ([]tokens.Token, error) {
+	if c, ok := requirements[token.Type]; ok {
+		if e := p.require(token.Literal.(string), c); e != nil {
+			return stack, e
+		}
+	}
+
+	switch token.Type {
+	case tokens.Args:
+		for _, arg := range p.args {
+			stack = append(stack, tokens.Token{Type: tokens.String, Literal: arg, Loc: token.Loc})
+		}
+		stack = append(stack, tokens.Token{Type: tokens.Int, Literal: int64(len(p.args)), Loc: token.Loc})
+
+	case tokens.Getenv:
+		stack, args, e := p.popStrings(token, stack, 1)
+		if e != nil {
+			return stack, e
+		}
+
+		value, ok := os.LookupEnv(args[0])
+		if !ok {
+			return append(stack, nilToken(token.Loc)), nil
+		}
+		return append(stack, tokens.Token{Type: tokens.String, Literal: value, Loc: token.Loc}), nil
+
+	case tokens.Setenv:
+		var args []string
+		var e error
+
+		stack, args, e = p.popStrings(token, stack, 2)
+		if e != nil {
+			return stack, e
+		}
+
+		if e := os.Setenv(args[1], args[0]); e != nil {
+			return stack, fmt.Errorf("Unable to set environment variable '%s': %v.", args[1], e)
+		}
+
+	case tokens.Exit:
+		var code tokens.Token
+		var e error
+
+		stack, code, e = Pop(stack)
+		if e != nil {
+			return stack, p.fail(token, err.StackUnderflow, fmt.Sprintf("The keyword '%s' requires value in stack. Stack is empty.", token.Literal))
+		}
+
+		status, ok := code.Literal.(int64)
+		if code.Type != tokens.Int || !ok {
+			return stack, p.fail(token, err.TypeMismatch, fmt.Sprintf("The keyword '%s' expects an int status code, but got '%s'.", token.Literal, code.Type))
+		}
+		if status < 0 || status > 255 {
+			return stack, p.fail(token, err.OutOfRange, fmt.Sprintf("The keyword '%s' expects a status code between 0 and 255, but got %d.", token.Literal, status))
+		}
+
+		p.exited = true
+		p.exitCode = int(status)
+	}
+
+	return stack, nil
+}
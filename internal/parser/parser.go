@@ -2,9 +2,13 @@ package parser
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/adaiasmagdiel/beremiz-go/internal/err"
@@ -12,12 +16,34 @@ import (
 )
 
 type Parser struct {
-	Tokens       []tokens.Token
-	pos          int
-	errorHandler func()
-	lines        []string
-	inLoop       int
-	isREPL       bool
+	Tokens   []tokens.Token
+	pos      int
+	inLoop   int
+	isREPL   bool
+	stack    []tokens.Token
+	input    *bufio.Reader
+	output   *bufio.Writer
+	errOut   io.Writer
+	caps     map[Capability]bool
+	args     []string
+	exited   bool
+	exitCode int
+	words    map[string]Word
+	limits   Limits
+	steps    int
+	frames   []Frame
+	// maxErrors stops checking the block structure once that many errors
+	// were found; 0 means no limit.
+	maxErrors int
+	hook      Hook
+	// throws holds the 'assert-throws' blocks being run, innermost last.
+	throws []throwsBlock
+	// depths holds, for each token, how many blocks are open around it.
+	depths []int
+	tree   *Node
+	// defs holds the body of every defined word, including the ones
+	// inherited from the program a State.Eval runs in.
+	defs map[string][]tokens.Token
 }
 
 type FlowAddr struct {
@@ -25,19 +51,98 @@ type FlowAddr struct {
 	token tokens.Token
 }
 
-func New(tokens []tokens.Token, errorHandler func(), lines []string, isREPL bool) *Parser {
-	if errorHandler == nil {
-		errorHandler = func() {}
+func New(tokens []tokens.Token, isREPL bool) *Parser {
+	return &Parser{
+		Tokens: tokens,
+		pos:    0,
+		inLoop: 0,
+		isREPL: isREPL,
+		input:  bufio.NewReader(os.Stdin),
+		output: bufio.NewWriter(os.Stdout),
+		errOut: os.Stderr,
 	}
+}
 
-	return &Parser{
-		Tokens:       tokens,
-		pos:          0,
-		errorHandler: errorHandler,
-		lines:        lines,
-		inLoop:       0,
-		isREPL:       isREPL,
+// SetOutput changes where write and writeln print to. It defaults to
+// os.Stdout.
+func (p *Parser) SetOutput(w io.Writer) {
+	p.output = bufio.NewWriter(w)
+}
+
+// SetErrorOutput changes where dump prints to. It defaults to os.Stderr.
+func (p *Parser) SetErrorOutput(w io.Writer) {
+	p.errOut = w
+}
+
+// SetMaxErrors makes Check stop after n errors. By default every error in
+// the block structure is reported.
+func (p *Parser) SetMaxErrors(n int) {
+	p.maxErrors = n
+}
+
+// Check reports every error that can be found without running the program:
+// stray 'end's, blocks that are never closed, 'define' without a name and
+// names that aren't defined.
+func (p *Parser) Check() error {
+	_, e := p.check()
+	return e
+}
+
+// check runs the checks behind Check and returns the definitions it found.
+func (p *Parser) check() (map[string][]tokens.Token, error) {
+	defs, e := p.handleControlFlow()
+	for name, body := range p.defs {
+		if _, ok := defs[name]; !ok {
+			defs[name] = body
+		}
 	}
+	p.defs = defs
+
+	errs := append(err.Diagnostics(e), p.checkNames(defs)...)
+	errs.Sort()
+	if p.maxErrors > 0 && len(errs) > p.maxErrors {
+		errs = errs[:p.maxErrors]
+	}
+
+	return defs, errs.Err()
+}
+
+// Depths returns, after Check, how many blocks are open around each token.
+// A block's opening keyword and its 'end' are outside it; everything
+// between them, 'do', 'elif' and 'else' included, is inside.
+func (p *Parser) Depths() []int {
+	return p.depths
+}
+
+// Stack returns the data stack, bottom first.
+func (p *Parser) Stack() []tokens.Token {
+	return p.stack
+}
+
+// fail builds the diagnostic reported for token, with the word calls that
+// led to it.
+func (p *Parser) fail(token tokens.Token, code err.Code, message string) *err.Diagnostic {
+	d := err.New(code, err.TokenSpan(token), message)
+	d.Trace = slices.Clone(p.frames)
+	return d
+}
+
+// wrap is like fail, but keeps cause reachable through errors.Is and
+// errors.As. Diagnostics are returned as they are.
+func (p *Parser) wrap(token tokens.Token, code err.Code, cause error) error {
+	var d *err.Diagnostic
+	if errors.As(cause, &d) {
+		return cause
+	}
+
+	var denied *PermissionError
+	if errors.As(cause, &denied) {
+		code = err.PermissionDenied
+	}
+
+	e := p.fail(token, code, cause.Error())
+	e.Err = cause
+	return e
 }
 
 func evalNumBin(op, a, b tokens.Token) (tokens.Token, error) {
@@ -173,7 +278,7 @@ func (p Parser) isAtEnd() bool {
 	return p.pos >= len(p.Tokens) || p.Tokens[p.pos].Type == tokens.EOF
 }
 
-func (p Parser) handleControlFlow() map[string][]tokens.Token {
+func (p *Parser) handleControlFlow() (map[string][]tokens.Token, error) {
 	addrInfo := []FlowAddr{}
 	var top FlowAddr
 	var e error
@@ -182,138 +287,213 @@ func (p Parser) handleControlFlow() map[string][]tokens.Token {
 	var keys []string
 
 	var blockStack []BlockType
+	// openers holds the token that opened each block in blockStack.
+	var openers []tokens.Token
+
+	// A bad token is reported and skipped, so the rest of the program is
+	// still checked.
+	var errs err.List
+	report := func(d *err.Diagnostic) {
+		errs = append(errs, d)
+	}
+
+	// collect adds token to the body of the innermost definition, if any.
+	collect := func(token tokens.Token) {
+		if len(keys) > 0 {
+			key := keys[len(keys)-1]
+			defs[key] = append(defs[key], token)
+		}
+	}
+
+	// open starts a block closed by a plain 'end'.
+	open := func(block BlockType, idx int, token tokens.Token) {
+		blockStack = append(blockStack, block)
+		openers = append(openers, token)
+		addrInfo = append(addrInfo, FlowAddr{addr: idx, token: token})
+	}
 
 	var idx int = 0
+	p.depths = make([]int, len(p.Tokens))
+	tree := newTreeBuilder()
+	p.tree = tree.root
 
 	for {
 		if idx >= len(p.Tokens) || p.Tokens[idx].Type == tokens.EOF {
 			break
 		}
+		if p.maxErrors > 0 && len(errs) >= p.maxErrors {
+			return defs, errs
+		}
 
 		token := p.Tokens[idx]
+		p.depths[idx] = len(blockStack)
 
 		switch token.Type {
 		case tokens.If:
-			blockStack = append(blockStack, BlockIf)
-			addrInfo = append(addrInfo, FlowAddr{addr: idx, token: token})
+			collect(token)
+			open(BlockIf, idx, token)
+			tree.block(NodeIf, &p.Tokens[idx], "")
 			idx++
 
 		case tokens.Elif, tokens.Else:
+			collect(token)
 			if len(blockStack) == 0 || blockStack[len(blockStack)-1] != BlockIf {
-				err.SyntaxError(token,
-					fmt.Sprintf("'%s' must follow an 'if ... do' or 'elif ... do' block.", token.Literal),
-					p.lines,
-				)
-				p.errorHandler()
-				break
+				report(p.fail(token, err.InvalidBlock, fmt.Sprintf("'%s' must follow an 'if ... do' or 'elif ... do' block.", token.Literal)))
+				idx++
+				continue
 			}
 
-			addrInfo, top, e = Pop(addrInfo)
-			if e != nil || top.token.Type != tokens.Do {
-				err.SyntaxError(token,
-					fmt.Sprintf("Invalid '%s' usage. Expected 'if ... do' or 'elif ... do'.", token.Literal),
-					p.lines,
-				)
-				p.errorHandler()
-				break
+			if len(addrInfo) == 0 || addrInfo[len(addrInfo)-1].token.Type != tokens.Do {
+				report(p.fail(token, err.InvalidBlock, fmt.Sprintf("Invalid '%s' usage. Expected 'if ... do' or 'elif ... do'.", token.Literal)))
+				idx++
+				continue
 			}
 
+			addrInfo, top, _ = Pop(addrInfo)
 			p.Tokens[top.addr].JmpTo = idx + 1
 			addrInfo = append(addrInfo, FlowAddr{addr: idx, token: token})
+			tree.branch(&p.Tokens[idx])
 			idx++
 
 		case tokens.For:
-			blockStack = append(blockStack, BlockFor)
-			addrInfo = append(addrInfo, FlowAddr{addr: idx, token: token})
+			collect(token)
+			open(BlockFor, idx, token)
+			tree.block(NodeFor, &p.Tokens[idx], "")
 			idx++
 
 		case tokens.Do:
+			collect(token)
 			addrInfo = append(addrInfo, FlowAddr{addr: idx, token: token})
+			tree.do(&p.Tokens[idx])
 			idx++
 
 		case tokens.Define:
-			blockStack = append(blockStack, BlockDefine)
-			addrInfo = append(addrInfo, FlowAddr{addr: idx, token: token})
+			open(BlockDefine, idx, token)
 
 			if idx+1 >= len(p.Tokens) || p.Tokens[idx+1].Type != tokens.Identifier {
-				err.SyntaxError(token,
-					fmt.Sprintf("Expected identifier after 'define' keyword, but got '%s'.",
-						strings.ToLower(string(p.Tokens[idx+1].Type))),
-					p.lines,
-				)
-				p.errorHandler()
-				break
+				tree.block(NodeDefine, &p.Tokens[idx], "")
+				next := tokens.EOF
+				if idx+1 < len(p.Tokens) {
+					next = p.Tokens[idx+1].Type
+				}
+				report(p.fail(token, err.MissingName, fmt.Sprintf("Expected identifier after 'define' keyword, but got '%s'.",
+					strings.ToLower(string(next)))))
+
+				// Keep the block open, so its 'end' still matches, but
+				// throw its body away.
+				keys = append(keys, "")
+				idx++
+				continue
 			}
 
 			key := p.Tokens[idx+1].Literal.(string)
 			defs[key] = []tokens.Token{}
 			keys = append(keys, key)
+			p.depths[idx+1] = len(blockStack)
+			tree.block(NodeDefine, &p.Tokens[idx], key)
 
 			idx += 2
 			continue
 
+		case tokens.Test:
+			open(BlockTest, idx, token)
+
+			if idx+1 >= len(p.Tokens) || p.Tokens[idx+1].Type != tokens.String {
+				tree.block(NodeTest, &p.Tokens[idx], "")
+				next := tokens.EOF
+				if idx+1 < len(p.Tokens) {
+					next = p.Tokens[idx+1].Type
+				}
+				report(p.fail(token, err.MissingName, fmt.Sprintf("Expected a string naming the test after 'test' keyword, but got '%s'.",
+					strings.ToLower(string(next)))))
+				idx++
+				continue
+			}
+			if len(blockStack) > 1 {
+				report(p.fail(token, err.InvalidBlock, "A 'test' block can't be nested inside another block."))
+			}
+			p.depths[idx+1] = len(blockStack)
+			tree.block(NodeTest, &p.Tokens[idx], p.Tokens[idx+1].Literal.(string))
+
+			idx += 2
+			continue
+
+		case tokens.AssertStack:
+			collect(token)
+			open(BlockAssertStack, idx, token)
+			tree.block(NodeAssertStack, &p.Tokens[idx], "")
+			idx++
+
+		case tokens.AssertThrows:
+			collect(token)
+			open(BlockAssertThrows, idx, token)
+			tree.block(NodeAssertThrows, &p.Tokens[idx], "")
+			idx++
+
 		case tokens.End:
+			tree.end(&p.Tokens[idx])
 			if len(blockStack) == 0 {
-				err.SyntaxError(token,
-					"Invalid 'end' usage. No matching block found.",
-					p.lines,
-				)
-				p.errorHandler()
-				break
+				report(p.fail(token, err.InvalidBlock, "Invalid 'end' usage. No matching block found."))
+				idx++
+				continue
 			}
 
 			current := blockStack[len(blockStack)-1]
 			blockStack = blockStack[:len(blockStack)-1]
+			openers = openers[:len(openers)-1]
+			p.depths[idx] = len(blockStack)
+			if current != BlockDefine {
+				collect(token)
+			}
 
 			switch current {
 			case BlockFor:
-				if len(addrInfo) < 2 {
-					err.SyntaxError(token,
-						"Invalid 'end' usage. No matching 'for .. do' block found.",
-						p.lines,
-					)
-					p.errorHandler()
+				if len(addrInfo) < 2 || addrInfo[len(addrInfo)-1].token.Type != tokens.Do {
+					report(p.fail(token, err.InvalidBlock, "Invalid 'end' usage. No matching 'for .. do' block found."))
+					addrInfo = dropBlock(addrInfo, tokens.For)
 					break
 				}
 				forFlow := addrInfo[len(addrInfo)-2]
 				doFlow := addrInfo[len(addrInfo)-1]
 
-				p.Tokens[idx].JmpTo = forFlow.addr
+				// Jump past the 'for' itself, so a loop starting at
+				// index 0 still gets a non-zero target.
+				p.Tokens[idx].JmpTo = forFlow.addr + 1
 				p.Tokens[doFlow.addr].JmpTo = idx + 1
 				addrInfo = addrInfo[:len(addrInfo)-2]
 
 			case BlockDefine:
 				if len(addrInfo) == 0 {
-					err.SyntaxError(token,
-						"Invalid 'end' usage. No matching 'define' block found.",
-						p.lines,
-					)
-					p.errorHandler()
+					report(p.fail(token, err.InvalidBlock, "Invalid 'end' usage. No matching 'define' block found."))
 					break
 				}
 				defineFlow := addrInfo[len(addrInfo)-1]
 				if defineFlow.token.Type != tokens.Define {
-					err.SyntaxError(token,
-						fmt.Sprintf("Mismatched 'end' block. Expected to close 'define', but found '%s'.",
-							defineFlow.token.Literal),
-						p.lines,
-					)
-					p.errorHandler()
+					report(p.fail(token, err.InvalidBlock, fmt.Sprintf("Mismatched 'end' block. Expected to close 'define', but found '%s'.",
+						defineFlow.token.Literal)))
+					addrInfo = dropBlock(addrInfo, tokens.Define)
+				} else {
+					p.Tokens[defineFlow.addr].JmpTo = idx + 1
+					addrInfo = addrInfo[:len(addrInfo)-1]
+				}
+				keys = keys[:len(keys)-1]
+
+			case BlockTest, BlockAssertStack, BlockAssertThrows:
+				name := blockKeywords[current]
+				opener := tokens.Keywords[name]
+				if len(addrInfo) == 0 || addrInfo[len(addrInfo)-1].token.Type != opener {
+					report(p.fail(token, err.InvalidBlock, fmt.Sprintf("Invalid 'end' usage. No matching '%s' block found.", name)))
+					addrInfo = dropBlock(addrInfo, opener)
 					break
 				}
-				p.Tokens[defineFlow.addr].JmpTo = idx + 1
+				p.Tokens[addrInfo[len(addrInfo)-1].addr].JmpTo = idx + 1
 				addrInfo = addrInfo[:len(addrInfo)-1]
-				keys = keys[:len(keys)-1]
 
 			case BlockIf:
 				for {
 					addrInfo, top, e = Pop(addrInfo)
 					if e != nil {
-						err.SyntaxError(token,
-							"Unbalanced 'end'. No matching 'if' block found.",
-							p.lines,
-						)
-						p.errorHandler()
+						report(p.fail(token, err.InvalidBlock, "Unbalanced 'end'. No matching 'if' block found."))
 						break
 					}
 					if top.token.Type != tokens.If {
@@ -328,24 +508,37 @@ func (p Parser) handleControlFlow() map[string][]tokens.Token {
 			continue
 
 		default:
-			if len(blockStack) > 0 && blockStack[len(blockStack)-1] == BlockDefine {
-				if len(keys) == 0 {
-					err.SyntaxError(token,
-						fmt.Sprintf("Expected identifier after 'define' keyword, but got '%s'.",
-							strings.ToLower(string(token.Type))),
-						p.lines,
-					)
-					p.errorHandler()
-					break
-				}
-				key := keys[len(keys)-1]
-				defs[key] = append(defs[key], token)
+			if len(blockStack) > 0 && blockStack[len(blockStack)-1] == BlockAssertStack && !isValue(token) {
+				report(p.fail(token, err.InvalidBlock, fmt.Sprintf("An 'assert-stack' block only holds values, but got '%s'.",
+					strings.ToLower(string(token.Type)))))
 			}
+			collect(token)
+			tree.instruction(&p.Tokens[idx])
 			idx++
 		}
 	}
 
-	return defs
+	for i := len(openers) - 1; i >= 0; i-- {
+		if p.maxErrors > 0 && len(errs) >= p.maxErrors {
+			break
+		}
+		report(p.fail(openers[i], err.UnclosedBlock, fmt.Sprintf("The '%s' block is never closed.", openers[i].Literal)).
+			Suggest("add an 'end' to close it"))
+	}
+
+	delete(defs, "")
+	return defs, errs.Err()
+}
+
+// dropBlock removes the addresses of the innermost block opened by opener,
+// so a bad block doesn't take the blocks around it down with it.
+func dropBlock(addrInfo []FlowAddr, opener tokens.TokenType) []FlowAddr {
+	for i := len(addrInfo) - 1; i >= 0; i-- {
+		if addrInfo[i].token.Type == opener {
+			return addrInfo[:i]
+		}
+	}
+	return addrInfo
 }
 
 func expandDefs(defs map[string][]tokens.Token) {
@@ -361,7 +554,7 @@ func expandDefs(defs map[string][]tokens.Token) {
 				if t.Type == tokens.Identifier {
 					name := fmt.Sprintf("%v", t.Literal)
 					if inner, ok := defs[name]; ok {
-						expanded = append(expanded, inner...)
+						expanded = append(expanded, callTokens(t, inner)...)
 						changed = true
 						continue
 					}
@@ -374,6 +567,16 @@ func expandDefs(defs map[string][]tokens.Token) {
 	}
 }
 
+// callTokens returns body wrapped in the Call and Return markers for a
+// call to the word named by ident.
+func callTokens(ident tokens.Token, body []tokens.Token) []tokens.Token {
+	call := make([]tokens.Token, 0, len(body)+2)
+	call = append(call, tokens.Token{Type: tokens.Call, Literal: ident.Literal, Loc: ident.Loc})
+	call = append(call, body...)
+	call = append(call, tokens.Token{Type: tokens.Return, Literal: ident.Literal, Loc: ident.Loc})
+	return call
+}
+
 func (p *Parser) expandBlocks(defs map[string][]tokens.Token) {
 	var expanded []tokens.Token
 
@@ -408,13 +611,7 @@ func (p *Parser) expandBlocks(defs map[string][]tokens.Token) {
 		if tok.Type == tokens.Identifier {
 			key := fmt.Sprintf("%v", tok.Literal)
 			if body, ok := defs[key]; ok {
-				for _, t := range body {
-					clone := t
-					if clone.Loc.File == "" {
-						clone.Loc = tok.Loc
-					}
-					expanded = append(expanded, clone)
-				}
+				expanded = append(expanded, callTokens(tok, body)...)
 				continue
 			}
 		}
@@ -425,22 +622,77 @@ func (p *Parser) expandBlocks(defs map[string][]tokens.Token) {
 	p.Tokens = expanded
 }
 
-func (p *Parser) Eval() {
-	var stack = []tokens.Token{}
+// Eval runs the program. It stops early with an error wrapping ctx.Err()
+// when ctx is done.
+func (p *Parser) Eval(ctx context.Context) error {
+	defer p.output.Flush()
 
-	defs := p.handleControlFlow()
+	defs, e := p.check()
+	if e != nil {
+		return e
+	}
 	expandDefs(defs)
 	p.expandBlocks(defs)
-	p.handleControlFlow()
+	if _, e := p.handleControlFlow(); e != nil {
+		return e
+	}
+
+	// An error caught by an 'assert-throws' block resumes the program
+	// after it.
+	for {
+		e := p.run(ctx)
+		if !p.catch(e) {
+			return e
+		}
+	}
+}
+
+// run runs the program from the current position until it ends or fails.
+func (p *Parser) run(ctx context.Context) error {
+	var stack = p.stack
+
+	outputBuffer := p.output
+	defer func() {
+		p.stack = stack
+	}()
 
-	var outputBuffer = bufio.NewWriter(os.Stdout)
+	var last tokens.Token
+	// depth is the size of the stack before the last instruction ran.
+	var depth int
 
 	for {
+		if p.steps > 0 {
+			if e := p.checkLimits(last, depth, stack); e != nil {
+				return e
+			}
+		}
+
 		if p.isAtEnd() {
 			break
 		}
 
 		token := p.peek()
+		last, depth = token, len(stack)
+
+		p.steps++
+		if p.steps%ctxCheckInterval == 0 {
+			if e := ctx.Err(); e != nil {
+				message := "Execution stopped: the evaluation was cancelled."
+				if errors.Is(e, context.DeadlineExceeded) {
+					message = "Execution stopped: the time limit was exceeded."
+				}
+				return p.stop(token, e, message)
+			}
+		}
+
+		// Definitions are jumped over rather than run, so hooks don't see
+		// them.
+		hooked := p.hook != nil && token.Type != tokens.Define && token.Type != tokens.Test
+		if hooked {
+			if e := p.hook.Before(p.state(token, stack)); e != nil {
+				return e
+			}
+		}
 
 		switch token.Type {
 		case tokens.Int,
@@ -462,12 +714,9 @@ func (p *Parser) Eval() {
 			tokens.Mod:
 
 			if len(stack) < 2 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The '%s' operator requires two operands in stack. Found %d.",
-					token.Literal, len(stack)),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal, len(stack)))
 			}
 
 			a := stack[len(stack)-2]
@@ -476,29 +725,22 @@ func (p *Parser) Eval() {
 
 			if (a.Type != tokens.Int && a.Type != tokens.Float) ||
 				(b.Type != tokens.Int && b.Type != tokens.Float) {
-				err.SyntaxError(token, fmt.Sprintf(
-					"Operator '%s' expects int or float.", token.Literal), p.lines)
-				p.errorHandler()
-				return
+				return p.fail(token, err.TypeMismatch, fmt.Sprintf(
+					"Operator '%s' expects int or float.", token.Literal))
 			}
 
 			res, e := evalNumBin(token, a, b)
 			if e != nil {
-				err.SyntaxError(token, e.Error(), p.lines)
-				p.errorHandler()
-				return
+				return p.wrap(token, err.ArithmeticError, e)
 			}
 			stack = append(stack, res)
 			p.consume()
 
 		case tokens.Concat:
 			if len(stack) < 2 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The '%s' operator requires two operands in stack. Found %d.",
-					token.Literal, len(stack)),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal, len(stack)))
 			}
 
 			a := stack[len(stack)-2]
@@ -516,12 +758,9 @@ func (p *Parser) Eval() {
 
 		case tokens.And, tokens.Or:
 			if len(stack) < 2 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The '%s' operator requires two operands in stack. Found %d.",
-					token.Literal, len(stack)),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal, len(stack)))
 			}
 
 			left := stack[len(stack)-2]
@@ -547,9 +786,7 @@ func (p *Parser) Eval() {
 					result = condRight
 				}
 			default:
-				err.SyntaxError(token, fmt.Sprintf("unsupported logical operator: %s", token.Literal), p.lines)
-				p.errorHandler()
-				return
+				return p.fail(token, err.TypeMismatch, fmt.Sprintf("unsupported logical operator: %s", token.Literal))
 			}
 
 			stack = append(stack, tokens.Token{
@@ -562,12 +799,9 @@ func (p *Parser) Eval() {
 
 		case tokens.Write, tokens.Writeln:
 			if len(stack) == 0 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The keyword '%s' requires value in stack. Stack is empty.",
-					token.Literal),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal))
 			}
 
 			a := stack[len(stack)-1]
@@ -584,11 +818,60 @@ func (p *Parser) Eval() {
 				outputBuffer.Flush()
 			}
 
+		case tokens.ReadLine, tokens.ReadAll, tokens.ReadNumber:
+			var value tokens.Token
+			var e error
+
+			outputBuffer.Flush()
+
+			switch token.Type {
+			case tokens.ReadLine:
+				value, e = p.readLine(token)
+			case tokens.ReadAll:
+				value, e = p.readAll(token)
+			case tokens.ReadNumber:
+				value, e = p.readNumber(token)
+			}
+
+			if e != nil {
+				return p.wrap(token, err.IOError, e)
+			}
+
+			stack = append(stack, value)
+			p.consume()
+
+		case tokens.ReadFile,
+			tokens.WriteFile,
+			tokens.AppendFile,
+			tokens.FileExists,
+			tokens.ListDir,
+			tokens.RemoveFile:
+			var e error
+
+			stack, e = p.evalFile(token, stack)
+			if e != nil {
+				return p.wrap(token, err.IOError, e)
+			}
+
+			p.consume()
+
+		case tokens.Args, tokens.Getenv, tokens.Setenv, tokens.Exit:
+			var e error
+
+			stack, e = p.evalOS(token, stack)
+			if e != nil {
+				return p.wrap(token, err.IOError, e)
+			}
+
+			p.consume()
+
+			if p.exited {
+				return nil
+			}
+
 		case tokens.Type:
 			if len(stack) == 0 {
-				err.SyntaxError(token, fmt.Sprintf("The keyword '%s' requires value in stack. Stack is empty.", token.Literal), p.lines)
-				p.errorHandler()
-				return
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf("The keyword '%s' requires value in stack. Stack is empty.", token.Literal))
 			}
 
 			a := stack[len(stack)-1]
@@ -610,9 +893,7 @@ func (p *Parser) Eval() {
 
 		case tokens.Dup:
 			if len(stack) == 0 {
-				err.SyntaxError(token, fmt.Sprintf("The keyword '%s' requires value in stack. Stack is empty.", token.Literal), p.lines)
-				p.errorHandler()
-				return
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf("The keyword '%s' requires value in stack. Stack is empty.", token.Literal))
 			}
 
 			a := stack[len(stack)-1]
@@ -622,12 +903,9 @@ func (p *Parser) Eval() {
 
 		case tokens.Swap:
 			if len(stack) < 2 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The '%s' operator requires two operands in stack. Found %d.",
-					token.Literal, len(stack)),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal, len(stack)))
 			}
 
 			p.consume()
@@ -639,21 +917,16 @@ func (p *Parser) Eval() {
 
 			stack, _, e = Pop(stack)
 			if e != nil {
-				err.SyntaxError(token, fmt.Sprintf("The keyword '%s' requires value in stack. Stack is empty.", token.Literal), p.lines)
-				p.errorHandler()
-				return
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf("The keyword '%s' requires value in stack. Stack is empty.", token.Literal))
 			}
 
 			p.consume()
 
 		case tokens.Over:
 			if len(stack) < 2 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The '%s' operator requires two operands in stack. Found %d.",
-					token.Literal, len(stack)),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal, len(stack)))
 			}
 
 			p.consume()
@@ -664,12 +937,9 @@ func (p *Parser) Eval() {
 
 		case tokens.Rot:
 			if len(stack) < 3 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The '%s' operator requires three operands in stack. Found %d.",
-					token.Literal, len(stack)),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal, len(stack)))
 			}
 
 			p.consume()
@@ -684,19 +954,20 @@ func (p *Parser) Eval() {
 			p.consume()
 			stack = append(stack, tokens.Token{
 				Type:    tokens.Int,
-				Literal: len(stack),
+				Literal: int64(len(stack)),
 				Loc:     token.Loc,
 			})
 
 		case tokens.Dump:
 			p.consume()
-			fmt.Printf("Stack[%d]:\n", len(stack))
+			outputBuffer.Flush()
+			fmt.Fprintf(p.errOut, "Stack[%d]:\n", len(stack))
 			for i, v := range stack {
-				fmt.Printf("  %d: (%s) %v", i, strings.ToLower(string(v.Type)), v.Literal)
+				fmt.Fprintf(p.errOut, "  %d: (%s) %v", i, strings.ToLower(string(v.Type)), v.Literal)
 				if i == len(stack)-1 {
-					fmt.Printf("  <- top")
+					fmt.Fprintf(p.errOut, "  <- top")
 				}
-				fmt.Println()
+				fmt.Fprintln(p.errOut)
 			}
 
 		case tokens.Clear:
@@ -705,12 +976,9 @@ func (p *Parser) Eval() {
 
 		case tokens.Eq:
 			if len(stack) < 2 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The '%s' operator requires two operands in stack. Found %d.",
-					token.Literal, len(stack)),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal, len(stack)))
 			}
 
 			p.consume()
@@ -728,12 +996,9 @@ func (p *Parser) Eval() {
 
 		case tokens.Neq:
 			if len(stack) < 2 {
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.StackUnderflow, fmt.Sprintf(
 					"The '%s' operator requires two operands in stack. Found %d.",
-					token.Literal, len(stack)),
-					p.lines)
-				p.errorHandler()
-				return
+					token.Literal, len(stack)))
 			}
 
 			p.consume()
@@ -758,18 +1023,47 @@ func (p *Parser) Eval() {
 
 		case tokens.Elif,
 			tokens.Else,
-			tokens.Define:
+			tokens.Define,
+			tokens.Test:
 			p.pos = p.consume().JmpTo
 
+		case tokens.Assert, tokens.AssertEq:
+			var e error
+
+			stack, e = p.evalAssert(token, stack)
+			if e != nil {
+				return e
+			}
+
+			p.consume()
+
+		case tokens.AssertStack:
+			var e error
+
+			stack, e = p.evalAssert(token, stack)
+			if e != nil {
+				return e
+			}
+
+			p.pos = token.JmpTo
+
+		case tokens.AssertThrows:
+			p.throws = append(p.throws, throwsBlock{
+				token:  token,
+				end:    token.JmpTo - 1,
+				stack:  slices.Clone(stack),
+				frames: len(p.frames),
+				inLoop: p.inLoop,
+			})
+			p.consume()
+
 		case tokens.Do:
 			var top tokens.Token
 			var e error
 			stack, top, e = Pop(stack)
 
 			if e != nil {
-				err.SyntaxError(token, "The 'do' keyword requires value in stack. Stack is empty.", p.lines)
-				p.errorHandler()
-				return
+				return p.fail(token, err.StackUnderflow, "The 'do' keyword requires value in stack. Stack is empty.")
 			}
 
 			var cond bool
@@ -785,43 +1079,71 @@ func (p *Parser) Eval() {
 			case tokens.String:
 				cond = top.Literal != ""
 			default:
-				err.SyntaxError(token, fmt.Sprintf(
+				return p.fail(token, err.TypeMismatch, fmt.Sprintf(
 					"Invalid condition type '%s' cannot be used in a boolean context", top.Type,
-				), p.lines)
-				p.errorHandler()
-				return
+				))
 			}
 
 			p.consume()
 
 			if !cond {
 				p.pos = token.JmpTo
+
+				// Leaving a loop: its 'end' is the last token skipped
+				exit := token.JmpTo - 1
+				if exit >= 0 && exit < len(p.Tokens) &&
+					p.Tokens[exit].Type == tokens.End && p.Tokens[exit].JmpTo > 0 && p.inLoop > 0 {
+					p.inLoop--
+				}
 			}
 
 		case tokens.End:
+			if n := len(p.throws); n > 0 && p.throws[n-1].end == p.pos {
+				block := p.throws[n-1]
+				p.throws = p.throws[:n-1]
+				return p.fail(block.token, err.AssertionFailed, "Assertion failed: the 'assert-throws' block ran without an error.")
+			}
+
 			if token.JmpTo > 0 {
 				p.pos = token.JmpTo
+			} else {
+				p.consume()
 			}
 
-			if p.inLoop > 0 {
-				p.inLoop--
-			}
+		case tokens.Call:
+			p.frames = append(p.frames, Frame{Name: token.Literal.(string), Loc: token.Loc})
+			p.consume()
 
+		case tokens.Return:
+			if len(p.frames) > 0 {
+				p.frames = p.frames[:len(p.frames)-1]
+			}
 			p.consume()
 
 		case tokens.Identifier:
-			// TODO: Add suport for identifiers
-			err.SyntaxError(token, fmt.Sprintf("Name '%s' is not defined.", token.Literal), p.lines)
-			p.errorHandler()
+			word, ok := p.words[token.Literal.(string)]
+			if !ok {
+				return p.undefined(token, err.UndefinedName, p.defs)
+			}
+
+			var e error
+			stack, e = p.callWord(word, token, stack)
+			if e != nil {
+				return p.wrap(token, err.HostError, e)
+			}
+
 			p.consume()
 
 		default:
-			err.Error(fmt.Sprintf("Not implemented case for TokenType '%s'.", token.Type))
-			fmt.Fprintf(os.Stderr, "\x1b[31m%s:%d:%d:\x1b[0m '%s'", token.Loc.File, token.Loc.Line, token.Loc.Col, token.Literal)
-			p.errorHandler()
-			return
+			return p.fail(token, err.NotImplemented, fmt.Sprintf("Not implemented case for TokenType '%s'.", token.Type))
+		}
+
+		if hooked {
+			if e := p.hook.After(p.state(token, stack)); e != nil {
+				return e
+			}
 		}
 	}
 
-	outputBuffer.Flush()
+	return nil
 }
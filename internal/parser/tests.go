@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Test is a 'test "name" ... end' block.
+type Test struct {
+	Name string
+	// Loc is where the 'test' keyword is.
+	Loc  tokens.Loc
+	Body []tokens.Token
+}
+
+// opens reports whether t starts a block closed by 'end'.
+func opens(t tokens.Token) bool {
+	switch t.Type {
+	case tokens.Define, tokens.If, tokens.For, tokens.Test, tokens.AssertStack, tokens.AssertThrows:
+		return true
+	}
+	return false
+}
+
+// blockEnd returns the index just past the 'end' closing the block opened
+// at ts[start], or len(ts) when it is never closed.
+func blockEnd(ts []tokens.Token, start int) int {
+	depth := 0
+	for i := start; i < len(ts); i++ {
+		switch {
+		case opens(ts[i]):
+			depth++
+		case ts[i].Type == tokens.End:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(ts)
+}
+
+// Tests returns the top-level test blocks of the program, in order.
+func Tests(ts []tokens.Token) []Test {
+	var tests []Test
+	for i := 0; i < len(ts); i++ {
+		if !opens(ts[i]) {
+			continue
+		}
+		end := blockEnd(ts, i)
+		if ts[i].Type == tokens.Test && i+1 < end && ts[i+1].Type == tokens.String {
+			tests = append(tests, Test{
+				Name: ts[i+1].Literal.(string),
+				Loc:  ts[i].Loc,
+				Body: ts[i+2 : max(end-1, i+2)],
+			})
+		}
+		i = end - 1
+	}
+	return tests
+}
+
+// TestProgram returns the program that runs test: the top-level
+// definitions of ts followed by the body of the test. The code outside
+// definitions and tests doesn't run.
+func TestProgram(ts []tokens.Token, test Test) []tokens.Token {
+	var program []tokens.Token
+	for i := 0; i < len(ts); i++ {
+		if !opens(ts[i]) {
+			continue
+		}
+		end := blockEnd(ts, i)
+		if ts[i].Type == tokens.Define {
+			program = append(program, ts[i:end]...)
+		}
+		i = end - 1
+	}
+
+	program = append(program, test.Body...)
+	return append(program, tokens.Token{Type: tokens.EOF, Loc: test.Loc})
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// NodeKind tells what a Node is.
+type NodeKind string
+
+const (
+	NodeProgram      NodeKind = "program"
+	NodeInstruction  NodeKind = "instruction"
+	NodeDefine       NodeKind = "define"
+	NodeIf           NodeKind = "if"
+	NodeBranch       NodeKind = "branch"
+	NodeFor          NodeKind = "for"
+	NodeTest         NodeKind = "test"
+	NodeAssertStack  NodeKind = "assert-stack"
+	NodeAssertThrows NodeKind = "assert-throws"
+)
+
+// Node is a piece of the block structure of a program.
+//
+// An 'if' holds one branch per 'if', 'elif' and 'else'; each branch but an
+// 'else' has a condition. A 'for' has a condition and a body, and the other
+// blocks only a body. Instructions are leaves.
+type Node struct {
+	Kind NodeKind
+	// Token is the instruction, or the keyword opening the node; nil for
+	// the program. Tokens point into the program, so they show the jumps
+	// resolved by Check.
+	Token *tokens.Token
+	// Name is the name of a 'define' or a 'test'.
+	Name     string
+	Cond     []*Node
+	Body     []*Node
+	Branches []*Node
+	// Do is the 'do' ending the condition, and End the 'end' closing the
+	// block; nil when missing.
+	Do  *tokens.Token
+	End *tokens.Token
+}
+
+// treeBuilder puts the tree together as handleControlFlow walks the
+// program.
+type treeBuilder struct {
+	root *Node
+	// open holds the blocks not closed yet, innermost last, and into the
+	// list each one is filling.
+	open []*Node
+	into []*[]*Node
+}
+
+func newTreeBuilder() *treeBuilder {
+	root := &Node{Kind: NodeProgram}
+	return &treeBuilder{root: root, open: []*Node{root}, into: []*[]*Node{&root.Body}}
+}
+
+// top returns the innermost open node.
+func (b *treeBuilder) top() *Node {
+	return b.open[len(b.open)-1]
+}
+
+// add appends node to the list being filled.
+func (b *treeBuilder) add(node *Node) {
+	list := b.into[len(b.into)-1]
+	*list = append(*list, node)
+}
+
+// instruction adds a leaf.
+func (b *treeBuilder) instruction(token *tokens.Token) {
+	b.add(&Node{Kind: NodeInstruction, Token: token})
+}
+
+// block opens a node of kind, filling its condition for 'if' and 'for'
+// and its body otherwise.
+func (b *treeBuilder) block(kind NodeKind, token *tokens.Token, name string) {
+	node := &Node{Kind: kind, Token: token, Name: name}
+	b.add(node)
+	b.open = append(b.open, node)
+
+	switch kind {
+	case NodeIf:
+		branch := &Node{Kind: NodeBranch, Token: token}
+		node.Branches = append(node.Branches, branch)
+		b.into = append(b.into, &branch.Cond)
+	case NodeFor:
+		b.into = append(b.into, &node.Cond)
+	default:
+		b.into = append(b.into, &node.Body)
+	}
+}
+
+// branch starts the 'elif' or 'else' branch of the innermost 'if'.
+func (b *treeBuilder) branch(token *tokens.Token) {
+	node := b.top()
+	branch := &Node{Kind: NodeBranch, Token: token}
+	node.Branches = append(node.Branches, branch)
+
+	if token.Type == tokens.Else {
+		b.into[len(b.into)-1] = &branch.Body
+	} else {
+		b.into[len(b.into)-1] = &branch.Cond
+	}
+}
+
+// do ends the condition of the innermost 'if' branch or 'for'. Any other
+// 'do' is kept as an instruction.
+func (b *treeBuilder) do(token *tokens.Token) {
+	node := b.top()
+	switch node.Kind {
+	case NodeIf:
+		branch := node.Branches[len(node.Branches)-1]
+		branch.Do = token
+		b.into[len(b.into)-1] = &branch.Body
+	case NodeFor:
+		node.Do = token
+		b.into[len(b.into)-1] = &node.Body
+	default:
+		b.instruction(token)
+	}
+}
+
+// end closes the innermost block.
+func (b *treeBuilder) end(token *tokens.Token) {
+	if len(b.open) == 1 {
+		b.instruction(token)
+		return
+	}
+	b.top().End = token
+	b.open = b.open[:len(b.open)-1]
+	b.into = b.into[:len(b.into)-1]
+}
+
+// Tree returns, after Check, the block structure of the program.
+func (p *Parser) Tree() *Node {
+	return p.tree
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
 )
@@ -15,8 +16,19 @@ const (
 	BlockIf
 	BlockFor
 	BlockDefine
+	BlockTest
+	BlockAssertStack
+	BlockAssertThrows
 )
 
+// blockKeywords names the keyword opening each block that only ends with a
+// plain 'end'.
+var blockKeywords = map[BlockType]string{
+	BlockTest:         "test",
+	BlockAssertStack:  "assert-stack",
+	BlockAssertThrows: "assert-throws",
+}
+
 func Pop[T any](s []T) ([]T, T, error) {
 	var zero T
 	if len(s) == 0 {
@@ -63,3 +75,36 @@ func toString(t tokens.Token) string {
 		return fmt.Sprintf("%v", v)
 	}
 }
+
+// isValue reports whether t is a literal value, pushed as it is.
+func isValue(t tokens.Token) bool {
+	switch t.Type {
+	case tokens.Int, tokens.Float, tokens.String, tokens.Bool, tokens.Nil:
+		return true
+	}
+	return false
+}
+
+// same reports whether a and b are the same value of the same type.
+func same(a, b tokens.Token) bool {
+	return a.Type == b.Type && a.Literal == b.Literal
+}
+
+// describe writes t the way it would be written in a program, with its
+// type.
+func describe(t tokens.Token) string {
+	value := toString(t)
+	if t.Type == tokens.String {
+		value = strconv.Quote(value)
+	}
+	return fmt.Sprintf("%s (%s)", value, strings.ToLower(string(t.Type)))
+}
+
+// describeAll writes values bottom first, like a stack.
+func describeAll(values []tokens.Token) string {
+	parts := make([]string, len(values))
+	for i, t := range values {
+		parts[i] = describe(t)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
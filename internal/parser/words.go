@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/err"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Word is a word implemented by the host program. Params lists the operand
+// types it expects, deepest first; an empty type accepts any value.
+// Requires lists the capabilities it needs to run.
+type Word struct {
+	Name     string
+	Params   []tokens.TokenType
+	Requires []Capability
+	Fn       func(token tokens.Token, stack []tokens.Token) ([]tokens.Token, error)
+}
+
+// SetWords makes words callable from the program by name.
+func (p *Parser) SetWords(words map[string]Word) {
+	p.words = words
+}
+
+// callWord checks the operands of word and runs it.
+func (p *Parser) callWord(word Word, token tokens.Token, stack []tokens.Token) ([]tokens.Token, error) {
+	if e := p.require(word.Name, word.Requires...); e != nil {
+		return stack, e
+	}
+
+	if len(stack) < len(word.Params) {
+		return stack, p.fail(token, err.StackUnderflow, fmt.Sprintf(
+			"The word '%s' requires %d value(s) in stack. Found %d.", word.Name, len(word.Params), len(stack)))
+	}
+
+	operands := stack[len(stack)-len(word.Params):]
+	for i, want := range word.Params {
+		if want != "" && operands[i].Type != want {
+			return stack, p.fail(token, err.TypeMismatch, fmt.Sprintf(
+				"The word '%s' expects %s as operand %d, but got %s.",
+				word.Name, strings.ToLower(string(want)), i+1, strings.ToLower(string(operands[i].Type))))
+		}
+	}
+
+	return word.Fn(token, stack)
+}
@@ -0,0 +1,163 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// WritePprof writes the call stacks of the program as a gzipped pprof
+// profile, so go tool pprof can show where the time went. Each sample has
+// two values: the instructions run and their wall time.
+//
+// The profile is encoded by hand, following profile.proto, to keep the
+// module free of dependencies.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	b := &builder{strings: map[string]int64{"": 0}, table: []string{""}}
+
+	var profile buffer
+
+	for _, t := range [][2]string{{"instructions", "count"}, {"wall", "nanoseconds"}} {
+		var vt buffer
+		vt.int(1, b.str(t[0]))
+		vt.int(2, b.str(t[1]))
+		profile.bytes(1, vt)
+	}
+
+	for _, s := range p.samples {
+		var ids []uint64
+		for _, loc := range s.stack {
+			ids = append(ids, b.location(loc))
+		}
+
+		var sample buffer
+		sample.packed(1, ids)
+		sample.packed(2, []uint64{uint64(s.instructions), uint64(s.time)})
+		profile.bytes(2, sample)
+	}
+
+	for _, loc := range b.locations {
+		profile.bytes(4, loc)
+	}
+	for _, fn := range b.functions {
+		profile.bytes(5, fn)
+	}
+
+	profile.int(9, p.began.UnixNano())
+	profile.int(10, int64(time.Since(p.began)))
+
+	var period buffer
+	period.int(1, b.str("wall"))
+	period.int(2, b.str("nanoseconds"))
+	profile.bytes(11, period)
+	profile.int(12, 1)
+
+	// Fields may come in any order, so the string table goes last, once
+	// everything above has added its strings.
+	for _, s := range b.table {
+		profile.bytes(6, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, e := gz.Write(profile); e != nil {
+		return e
+	}
+	return gz.Close()
+}
+
+// builder numbers the strings, functions and locations of a profile.
+type builder struct {
+	strings map[string]int64
+	table   []string
+
+	functionIDs map[string]uint64
+	functions   []buffer
+	locationIDs map[location]uint64
+	locations   []buffer
+}
+
+// str returns the index of s in the string table.
+func (b *builder) str(s string) int64 {
+	if i, ok := b.strings[s]; ok {
+		return i
+	}
+	i := int64(len(b.table))
+	b.strings[s] = i
+	b.table = append(b.table, s)
+	return i
+}
+
+func (b *builder) function(name, file string) uint64 {
+	key := name + "\x00" + file
+	if id, ok := b.functionIDs[key]; ok {
+		return id
+	}
+	if b.functionIDs == nil {
+		b.functionIDs = map[string]uint64{}
+	}
+
+	id := uint64(len(b.functions) + 1)
+	var fn buffer
+	fn.uint(1, id)
+	fn.int(2, b.str(name))
+	fn.int(3, b.str(name))
+	fn.int(4, b.str(file))
+	b.functions = append(b.functions, fn)
+	b.functionIDs[key] = id
+	return id
+}
+
+func (b *builder) location(loc location) uint64 {
+	if id, ok := b.locationIDs[loc]; ok {
+		return id
+	}
+	if b.locationIDs == nil {
+		b.locationIDs = map[location]uint64{}
+	}
+
+	id := uint64(len(b.locations) + 1)
+	var line buffer
+	line.uint(1, b.function(loc.function, loc.file))
+	line.int(2, int64(loc.line))
+
+	var l buffer
+	l.uint(1, id)
+	l.bytes(4, line)
+	b.locations = append(b.locations, l)
+	b.locationIDs[loc] = id
+	return id
+}
+
+// buffer is a protocol buffer message being encoded.
+type buffer []byte
+
+func (b *buffer) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *buffer) uint(field int, v uint64) {
+	b.varint(uint64(field) << 3)
+	b.varint(v)
+}
+
+func (b *buffer) int(field int, v int64) {
+	b.uint(field, uint64(v))
+}
+
+func (b *buffer) bytes(field int, v []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *buffer) packed(field int, vs []uint64) {
+	var p buffer
+	for _, v := range vs {
+		p.varint(v)
+	}
+	b.bytes(field, p)
+}
@@ -0,0 +1,181 @@
+// Package profile measures where a Beremiz program spends its time: how
+// many instructions run and how long they take, per defined word and per
+// source line. It can also write the measurements as a pprof profile.
+package profile
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+// mainFunction names the code outside any word in profiles. pprof shows
+// names in angle brackets as unknown, so it isn't "<main>" as in tracebacks.
+const mainFunction = "(main)"
+
+// Stat is what was measured for a word or a line.
+type Stat struct {
+	// Calls counts the calls of a word. Lines are never called.
+	Calls int
+	// Instructions and Time count what ran, including inside the words a
+	// word calls.
+	Instructions int
+	Time         time.Duration
+	// Self is the part of Time a word spent in its own instructions. It is
+	// unset for lines.
+	Self time.Duration
+}
+
+// Line is a line of source.
+type Line struct {
+	File string
+	Line int
+}
+
+// Profiler is a beremiz.Hook measuring each instruction, from just before it
+// runs to just after.
+type Profiler struct {
+	Words map[string]*Stat
+	Lines map[Line]*Stat
+
+	start   time.Time
+	samples map[string]*sample
+	began   time.Time
+}
+
+// sample is the measurements of one call stack, for pprof.
+type sample struct {
+	// stack is the innermost location first.
+	stack        []location
+	instructions int64
+	time         time.Duration
+}
+
+// location is a line of source inside a word.
+type location struct {
+	function string
+	file     string
+	line     int
+}
+
+// New returns an empty Profiler.
+func New() *Profiler {
+	return &Profiler{
+		Words:   map[string]*Stat{},
+		Lines:   map[Line]*Stat{},
+		samples: map[string]*sample{},
+		began:   time.Now(),
+	}
+}
+
+func (p *Profiler) Before(ev *beremiz.Event) error {
+	if ev.Kind == beremiz.Call {
+		p.word(ev.Word).Calls++
+	}
+	p.start = time.Now()
+	return nil
+}
+
+func (p *Profiler) After(ev *beremiz.Event) error {
+	elapsed := time.Since(p.start)
+	if ev.Kind != beremiz.Instruction {
+		return nil
+	}
+
+	line := Line{File: ev.Loc.File, Line: ev.Loc.Line}
+	if p.Lines[line] == nil {
+		p.Lines[line] = &Stat{}
+	}
+	p.Lines[line].Instructions++
+	p.Lines[line].Time += elapsed
+
+	// A recursive word counts once per instruction, however deep it is.
+	seen := map[string]bool{}
+	for _, f := range ev.Frames {
+		if seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+
+		stat := p.word(f.Name)
+		stat.Instructions++
+		stat.Time += elapsed
+	}
+	if len(ev.Frames) > 0 {
+		p.word(ev.Frames[len(ev.Frames)-1].Name).Self += elapsed
+	}
+
+	p.sample(ev, elapsed)
+	return nil
+}
+
+func (p *Profiler) word(name string) *Stat {
+	if p.Words[name] == nil {
+		p.Words[name] = &Stat{}
+	}
+	return p.Words[name]
+}
+
+// sample adds an instruction to the sample of its call stack.
+func (p *Profiler) sample(ev *beremiz.Event, elapsed time.Duration) {
+	function := func(depth int) string {
+		if depth == 0 {
+			return mainFunction
+		}
+		return ev.Frames[depth-1].Name
+	}
+
+	stack := []location{{function(len(ev.Frames)), ev.Loc.File, ev.Loc.Line}}
+	for i := len(ev.Frames) - 1; i >= 0; i-- {
+		loc := ev.Frames[i].Loc
+		stack = append(stack, location{function(i), loc.File, loc.Line})
+	}
+
+	key := fmt.Sprint(stack)
+	s, ok := p.samples[key]
+	if !ok {
+		s = &sample{stack: stack}
+		p.samples[key] = s
+	}
+	s.instructions++
+	s.time += elapsed
+}
+
+// Report writes the words and the lines, the slowest first.
+func (p *Profiler) Report(w io.Writer) {
+	words := slices.Collect(maps.Keys(p.Words))
+	slices.SortFunc(words, func(a, b string) int {
+		return cmp.Or(cmp.Compare(p.Words[b].Time, p.Words[a].Time), cmp.Compare(a, b))
+	})
+
+	fmt.Fprintf(w, "%-24s %8s %12s %12s %12s\n", "Word", "Calls", "Instructions", "Time", "Self")
+	for _, name := range words {
+		s := p.Words[name]
+		fmt.Fprintf(w, "%-24s %8d %12d %12s %12s\n", name, s.Calls, s.Instructions, s.Time, s.Self)
+	}
+
+	lines := slices.Collect(maps.Keys(p.Lines))
+	slices.SortFunc(lines, func(a, b Line) int {
+		return cmp.Or(
+			cmp.Compare(p.Lines[b].Time, p.Lines[a].Time),
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Line, b.Line),
+		)
+	})
+
+	var total Stat
+	fmt.Fprintf(w, "\n%-24s %8s %12s %12s\n", "Line", "", "Instructions", "Time")
+	for _, line := range lines {
+		s := p.Lines[line]
+		fmt.Fprintf(w, "%-24s %8s %12d %12s\n", fmt.Sprintf("%s:%d", line.File, line.Line), "", s.Instructions, s.Time)
+		total.Instructions += s.Instructions
+		total.Time += s.Time
+	}
+
+	fmt.Fprintf(w, "\n%d instructions in %s\n", total.Instructions, total.Time)
+}
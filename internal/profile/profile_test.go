@@ -0,0 +1,211 @@
+package profile_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/profile"
+)
+
+const source = `define square dup * end
+define twice square square end
+2 twice 3 square
+`
+
+func run(t *testing.T) *profile.Profiler {
+	t.Helper()
+	p := profile.New()
+	if _, e := beremiz.New(beremiz.WithHook(p)).EvalSource(context.Background(), "t.brz", source); e != nil {
+		t.Fatalf("EvalSource: %v", e)
+	}
+	return p
+}
+
+func TestCounts(t *testing.T) {
+	p := run(t)
+
+	words := map[string][2]int{}
+	for name, s := range p.Words {
+		words[name] = [2]int{s.Calls, s.Instructions}
+	}
+	// square runs twice inside twice, whose count includes them.
+	want := map[string][2]int{"square": {3, 6}, "twice": {1, 4}}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("calls and instructions = %v, want %v", words, want)
+	}
+
+	lines := map[int]int{}
+	for line, s := range p.Lines {
+		lines[line.Line] = s.Instructions
+	}
+	// Calls aren't instructions: line 3 only counts its two numbers.
+	if want := map[int]int{1: 6, 3: 2}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("instructions per line = %v, want %v", lines, want)
+	}
+}
+
+func TestReportOrder(t *testing.T) {
+	p := profile.New()
+	p.Words["fast"] = &profile.Stat{Calls: 9, Instructions: 90, Time: time.Millisecond}
+	p.Words["slow"] = &profile.Stat{Calls: 1, Instructions: 10, Time: 3 * time.Millisecond}
+	p.Words["tie"] = &profile.Stat{Calls: 1, Instructions: 1, Time: time.Millisecond}
+	p.Lines[profile.Line{File: "t.brz", Line: 4}] = &profile.Stat{Instructions: 5, Time: time.Millisecond}
+	p.Lines[profile.Line{File: "t.brz", Line: 2}] = &profile.Stat{Instructions: 1, Time: 2 * time.Millisecond}
+	p.Lines[profile.Line{File: "t.brz", Line: 1}] = &profile.Stat{Instructions: 2, Time: time.Millisecond}
+
+	var out bytes.Buffer
+	p.Report(&out)
+
+	var first []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			first = append(first, fields[0])
+		}
+	}
+	// The slowest first; ties by name, or by file and line.
+	want := []string{
+		"Word", "slow", "fast", "tie",
+		"Line", "t.brz:2", "t.brz:1", "t.brz:4",
+		"8",
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("report rows = %q, want %q\n%s", first, want, out.String())
+	}
+	if !strings.HasSuffix(out.String(), "\n8 instructions in 4ms\n") {
+		t.Errorf("report = %q, want it to end with the totals", out.String())
+	}
+}
+
+func TestPprof(t *testing.T) {
+	var out bytes.Buffer
+	if e := run(t).WritePprof(&out); e != nil {
+		t.Fatalf("WritePprof: %v", e)
+	}
+
+	gz, e := gzip.NewReader(&out)
+	if e != nil {
+		t.Fatalf("not gzipped: %v", e)
+	}
+	data, e := io.ReadAll(gz)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	fields, e := decode(data)
+	if e != nil {
+		t.Fatalf("profile: %v", e)
+	}
+
+	var table []string
+	var instructions uint64
+	var samples int
+	for _, f := range fields {
+		switch f.num {
+		case 6: // string_table
+			table = append(table, string(f.data))
+		case 2: // sample
+			samples++
+			sample, e := decode(f.data)
+			if e != nil {
+				t.Fatalf("sample: %v", e)
+			}
+			for _, sf := range sample {
+				if sf.num != 2 { // value
+					continue
+				}
+				values, e := varints(sf.data)
+				if e != nil || len(values) != 2 {
+					t.Fatalf("sample values = %v, %v; want instructions and time", values, e)
+				}
+				instructions += values[0]
+			}
+		}
+	}
+
+	if len(table) == 0 || table[0] != "" {
+		t.Errorf("string table = %q, want it to start with an empty string", table)
+	}
+	for _, s := range []string{"instructions", "wall", "square", "twice", "(main)", "t.brz"} {
+		if !slices.Contains(table, s) {
+			t.Errorf("string table = %q, want it to hold %q", table, s)
+		}
+	}
+	// One sample per call stack, by line: main, square called from main,
+	// and square called from twice.
+	if samples != 3 {
+		t.Errorf("got %d samples, want 3", samples)
+	}
+	if instructions != 8 {
+		t.Errorf("samples count %d instructions, want 8", instructions)
+	}
+}
+
+// field is a decoded protocol buffer field: a varint or bytes.
+type field struct {
+	num    int
+	varint uint64
+	data   []byte
+}
+
+// decode reads the fields of a protocol buffer message, failing on
+// anything malformed.
+func decode(b []byte) ([]field, error) {
+	var fields []field
+	for len(b) > 0 {
+		key, n := uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("bad field key")
+		}
+		b = b[n:]
+
+		f := field{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			if f.varint, n = uvarint(b); n <= 0 {
+				return nil, errors.New("bad varint")
+			}
+			b = b[n:]
+		case 2:
+			size, n := uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return nil, errors.New("bad length")
+			}
+			f.data = b[n : n+int(size)]
+			b = b[n+int(size):]
+		default:
+			return nil, errors.New("unexpected wire type")
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// varints reads packed varints.
+func varints(b []byte) ([]uint64, error) {
+	var values []uint64
+	for len(b) > 0 {
+		v, n := uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("bad varint")
+		}
+		values = append(values, v)
+		b = b[n:]
+	}
+	return values, nil
+}
+
+// uvarint reads a varint and its length, which is 0 or less when it is
+// malformed.
+func uvarint(b []byte) (uint64, int) {
+	return binary.Uvarint(b)
+}
@@ -24,6 +24,22 @@ const (
 	Type    TokenType = "TYPE"
 	Define  TokenType = "DEFINE"
 
+	ReadLine   TokenType = "READ_LINE"
+	ReadAll    TokenType = "READ_ALL"
+	ReadNumber TokenType = "READ_NUMBER"
+
+	ReadFile   TokenType = "READ_FILE"
+	WriteFile  TokenType = "WRITE_FILE"
+	AppendFile TokenType = "APPEND_FILE"
+	FileExists TokenType = "FILE_EXISTS"
+	ListDir    TokenType = "LIST_DIR"
+	RemoveFile TokenType = "REMOVE_FILE"
+
+	Args   TokenType = "ARGS"
+	Getenv TokenType = "GETENV"
+	Setenv TokenType = "SETENV"
+	Exit   TokenType = "EXIT"
+
 	Plus   TokenType = "PLUS"
 	Minus  TokenType = "MINUS"
 	Times  TokenType = "TIMES"
@@ -52,6 +68,17 @@ const (
 	Clear TokenType = "CLEAR"
 	Rot   TokenType = "ROT"
 
+	Test         TokenType = "TEST"
+	Assert       TokenType = "ASSERT"
+	AssertEq     TokenType = "ASSERT_EQ"
+	AssertStack  TokenType = "ASSERT_STACK"
+	AssertThrows TokenType = "ASSERT_THROWS"
+
+	// Call and Return wrap the inlined body of a defined word, so the
+	// evaluator can keep track of call frames. The lexer never emits them.
+	Call   TokenType = "CALL"
+	Return TokenType = "RETURN"
+
 	EOF TokenType = "EOF"
 )
 
@@ -66,6 +93,29 @@ type Token struct {
 	Literal any
 	Loc     Loc
 	JmpTo   int
+	// Len is how many runes the token spans in the source, or 0 for tokens
+	// made while running the program.
+	Len int
+}
+
+// TriviaKind tells what a piece of Trivia is.
+type TriviaKind uint8
+
+const (
+	Whitespace TriviaKind = iota
+	// LineComment runs from '#' to the end of the line, without the
+	// newline.
+	LineComment
+	// BlockComment runs from '#[' to the next '#'.
+	BlockComment
+)
+
+// Trivia is source text between tokens that doesn't change what the
+// program does. The lexer only keeps it when asked to.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+	Loc  Loc
 }
 
 var Operators map[string]TokenType = map[string]TokenType{
@@ -79,7 +129,7 @@ var Operators map[string]TokenType = map[string]TokenType{
 	".": Concat,
 }
 
-func IsOperator(args ...byte) bool {
+func IsOperator(args ...rune) bool {
 	_, ok := Operators[string(args)]
 	return ok
 }
@@ -90,6 +140,22 @@ var Keywords map[string]TokenType = map[string]TokenType{
 	"type":    Type,
 	"define":  Define,
 
+	"read-line":   ReadLine,
+	"read-all":    ReadAll,
+	"read-number": ReadNumber,
+
+	"read-file":   ReadFile,
+	"write-file":  WriteFile,
+	"append-file": AppendFile,
+	"file-exists": FileExists,
+	"list-dir":    ListDir,
+	"remove-file": RemoveFile,
+
+	"args":   Args,
+	"getenv": Getenv,
+	"setenv": Setenv,
+	"exit":   Exit,
+
 	"nil": Nil,
 
 	"for":  For,
@@ -113,6 +179,12 @@ var Keywords map[string]TokenType = map[string]TokenType{
 	"and": And,
 	"not": Not,
 	"or":  Or,
+
+	"test":          Test,
+	"assert":        Assert,
+	"assert-eq":     AssertEq,
+	"assert-stack":  AssertStack,
+	"assert-throws": AssertThrows,
 }
 
 func IsKeyword(content string) bool {
@@ -0,0 +1,198 @@
+// Package trace prints every instruction a Beremiz program runs, with where
+// it is written and the data stack it leaves, like the trace mode of a Forth
+// system.
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/debug"
+)
+
+// Format is how each traced instruction is written.
+type Format string
+
+const (
+	// FormatText writes one aligned line per instruction, indented by call
+	// depth.
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per line.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the format called name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown trace format '%s' (expected text or json)", name)
+}
+
+// Lines is a range of source lines. A zero To has no upper bound.
+type Lines struct {
+	From, To int
+}
+
+// ParseLines reads a range of lines: "N", "N-M" or "N-".
+func ParseLines(spec string) (Lines, error) {
+	from, to, found := strings.Cut(spec, "-")
+
+	var lines Lines
+	var e error
+	if lines.From, e = strconv.Atoi(from); e != nil || lines.From < 1 {
+		return Lines{}, fmt.Errorf("invalid line range '%s'", spec)
+	}
+
+	switch {
+	case !found:
+		lines.To = lines.From
+	case to != "":
+		if lines.To, e = strconv.Atoi(to); e != nil || lines.To < lines.From {
+			return Lines{}, fmt.Errorf("invalid line range '%s'", spec)
+		}
+	}
+	return lines, nil
+}
+
+func (l Lines) contains(line int) bool {
+	return line >= l.From && (l.To == 0 || line <= l.To)
+}
+
+// Tracer is a beremiz.Hook that writes each instruction once it ran.
+type Tracer struct {
+	w      io.Writer
+	format Format
+
+	// Word, when set, only traces what runs inside calls of that word.
+	Word string
+	// Lines, when set, only traces instructions written on those lines.
+	Lines *Lines
+}
+
+// New returns a Tracer writing to w in format.
+func New(w io.Writer, format Format) *Tracer {
+	return &Tracer{w: w, format: format}
+}
+
+func (t *Tracer) Before(ev *beremiz.Event) error {
+	return nil
+}
+
+func (t *Tracer) After(ev *beremiz.Event) error {
+	if !t.matches(ev) {
+		return nil
+	}
+
+	// The program's own output comes first, so both read in order.
+	ev.Flush()
+
+	if t.format == FormatJSON {
+		return t.writeJSON(ev)
+	}
+	return t.writeText(ev)
+}
+
+// matches reports whether ev passes the filters.
+func (t *Tracer) matches(ev *beremiz.Event) bool {
+	if t.Lines != nil && !t.Lines.contains(ev.Loc.Line) {
+		return false
+	}
+	if t.Word == "" {
+		return true
+	}
+
+	if ev.Kind != beremiz.Instruction && ev.Word == t.Word {
+		return true
+	}
+	for _, f := range ev.Frames {
+		if f.Name == t.Word {
+			return true
+		}
+	}
+	return false
+}
+
+// kinds names each event kind in the trace.
+var kinds = map[beremiz.EventKind]string{
+	beremiz.Instruction: "instruction",
+	beremiz.Call:        "call",
+	beremiz.Return:      "return",
+}
+
+func (t *Tracer) writeText(ev *beremiz.Event) error {
+	word := ev.Word
+	depth := len(ev.Frames)
+	switch ev.Kind {
+	case beremiz.Call:
+		// The call's own frame is already active.
+		word, depth = "> "+word, depth-1
+	case beremiz.Return:
+		word = "< " + word
+	}
+
+	loc := fmt.Sprintf("%s:%d:%d", ev.Loc.File, ev.Loc.Line, ev.Loc.Col)
+	_, e := fmt.Fprintf(t.w, "%-20s %-24s %s\n", loc, strings.Repeat("  ", max(depth, 0))+word, formatStack(ev.Stack()))
+	return e
+}
+
+type jsonEvent struct {
+	Kind   string   `json:"kind"`
+	Word   string   `json:"word"`
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+	Col    int      `json:"col"`
+	Frames []string `json:"frames"`
+	Stack  []any    `json:"stack"`
+}
+
+func (t *Tracer) writeJSON(ev *beremiz.Event) error {
+	frames := []string{}
+	for _, f := range ev.Frames {
+		frames = append(frames, f.Name)
+	}
+
+	stack := []any{}
+	for _, v := range ev.Stack() {
+		// JSON has no infinities or NaN.
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			v = debug.FormatValue(f)
+		}
+		stack = append(stack, v)
+	}
+
+	line, e := json.Marshal(jsonEvent{
+		Kind:   kinds[ev.Kind],
+		Word:   ev.Word,
+		File:   ev.Loc.File,
+		Line:   ev.Loc.Line,
+		Col:    ev.Loc.Col,
+		Frames: frames,
+		Stack:  stack,
+	})
+	if e != nil {
+		return e
+	}
+	_, e = fmt.Fprintf(t.w, "%s\n", line)
+	return e
+}
+
+// formatStack writes stack bottom first, quoting strings so they can't be
+// mistaken for words.
+func formatStack(stack []any) string {
+	values := make([]string, len(stack))
+	for i, v := range stack {
+		if s, ok := v.(string); ok {
+			values[i] = strconv.Quote(s)
+		} else {
+			values[i] = debug.FormatValue(v)
+		}
+	}
+	return "[" + strings.Join(values, " ") + "]"
+}
@@ -0,0 +1,184 @@
+package trace_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+	"github.com/adaiasmagdiel/beremiz-go/internal/trace"
+)
+
+const source = `define square
+    dup *
+end
+define twice
+    square square
+end
+2 twice "a"
+`
+
+// run traces source with tracer and returns what it wrote to out.
+func run(t *testing.T, tracer *trace.Tracer, out *bytes.Buffer) string {
+	t.Helper()
+	if _, e := beremiz.New(beremiz.WithHook(tracer)).EvalSource(context.Background(), "t.brz", source); e != nil {
+		t.Fatalf("EvalSource: %v", e)
+	}
+	return out.String()
+}
+
+// words returns the second column of a text trace: the indented words.
+func words(text string) []string {
+	var words []string
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		words = append(words, strings.TrimRight(line[21:46], " "))
+	}
+	return words
+}
+
+func TestText(t *testing.T) {
+	var out bytes.Buffer
+	got := run(t, trace.New(&out, trace.FormatText), &out)
+
+	want := `t.brz:7:1            2                        [2]
+t.brz:7:3            > twice                  [2]
+t.brz:5:5              > square               [2]
+t.brz:2:5                dup                  [2 2]
+t.brz:2:9                *                    [4]
+t.brz:5:5              < square               [4]
+t.brz:5:12             > square               [4]
+t.brz:2:5                dup                  [4 4]
+t.brz:2:9                *                    [16]
+t.brz:5:12             < square               [16]
+t.brz:7:3            < twice                  [16]
+t.brz:7:9            "a"                      [16 "a"]
+`
+	if got != want {
+		t.Errorf("trace =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	cases := []struct {
+		name  string
+		word  string
+		lines string
+		want  []string
+	}{
+		{"word", "square", "", []string{
+			"  > square", "    dup", "    *", "  < square",
+			"  > square", "    dup", "    *", "  < square",
+		}},
+		{"outer word", "twice", "", []string{
+			"> twice", "  > square", "    dup", "    *", "  < square",
+			"  > square", "    dup", "    *", "  < square", "< twice",
+		}},
+		{"one line", "", "2", []string{"    dup", "    *", "    dup", "    *"}},
+		{"open range", "", "5-", []string{
+			"2", "> twice", "  > square", "  < square",
+			"  > square", "  < square", "< twice", `"a"`,
+		}},
+		{"word and lines", "twice", "7", []string{"> twice", "< twice"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tracer := trace.New(&out, trace.FormatText)
+			tracer.Word = c.word
+			if c.lines != "" {
+				lines, e := trace.ParseLines(c.lines)
+				if e != nil {
+					t.Fatal(e)
+				}
+				tracer.Lines = &lines
+			}
+
+			if got := words(run(t, tracer, &out)); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("words = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var out bytes.Buffer
+	text := run(t, trace.New(&out, trace.FormatJSON), &out)
+
+	type event struct {
+		Kind   string   `json:"kind"`
+		Word   string   `json:"word"`
+		File   string   `json:"file"`
+		Line   int      `json:"line"`
+		Col    int      `json:"col"`
+		Frames []string `json:"frames"`
+		Stack  []any    `json:"stack"`
+	}
+	var events []event
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		var ev event
+		if e := json.Unmarshal(scanner.Bytes(), &ev); e != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), e)
+		}
+		events = append(events, ev)
+	}
+
+	if len(events) != 12 {
+		t.Fatalf("got %d events, want 12", len(events))
+	}
+	want := map[int]event{
+		0:  {"instruction", "2", "t.brz", 7, 1, []string{}, []any{2.0}},
+		2:  {"call", "square", "t.brz", 5, 5, []string{"twice", "square"}, []any{2.0}},
+		3:  {"instruction", "dup", "t.brz", 2, 5, []string{"twice", "square"}, []any{2.0, 2.0}},
+		10: {"return", "twice", "t.brz", 7, 3, []string{}, []any{16.0}},
+		11: {"instruction", `"a"`, "t.brz", 7, 9, []string{}, []any{16.0, "a"}},
+	}
+	for i, ev := range want {
+		if !reflect.DeepEqual(events[i], ev) {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], ev)
+		}
+	}
+}
+
+func TestJSONNonFinite(t *testing.T) {
+	var out bytes.Buffer
+	tracer := trace.New(&out, trace.FormatJSON)
+	if _, e := beremiz.New(beremiz.WithHook(tracer)).Eval(context.Background(), `9999999999.0 dup * dup * dup * dup * dup *`); e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	var ev struct{ Stack []any }
+	if e := json.Unmarshal([]byte(lines[len(lines)-1]), &ev); e != nil {
+		t.Fatalf("%q: %v", lines[len(lines)-1], e)
+	}
+	if len(ev.Stack) != 1 {
+		t.Errorf("stack = %v, want the infinity written as a string", ev.Stack)
+	} else if _, ok := ev.Stack[0].(string); !ok {
+		t.Errorf("stack = %v, want the infinity written as a string", ev.Stack)
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	valid := map[string]trace.Lines{
+		"3":   {From: 3, To: 3},
+		"3-5": {From: 3, To: 5},
+		"3-":  {From: 3},
+	}
+	for spec, want := range valid {
+		if got, e := trace.ParseLines(spec); e != nil || got != want {
+			t.Errorf("ParseLines(%q) = %v, %v; want %v", spec, got, e, want)
+		}
+	}
+
+	for _, spec := range []string{"", "0", "x", "5-3", "-3", "3-x"} {
+		if _, e := trace.ParseLines(spec); e == nil {
+			t.Errorf("ParseLines(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package wire reads and writes the messages of the Debug Adapter Protocol
+// and the Language Server Protocol: JSON bodies framed by a Content-Length
+// header.
+package wire
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Read reads the body of one message.
+func Read(r *bufio.Reader) ([]byte, error) {
+	length := -1
+
+	for {
+		line, e := r.ReadString('\n')
+		if e != nil {
+			return nil, e
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, e = strconv.Atoi(strings.TrimSpace(value))
+			if e != nil {
+				return nil, fmt.Errorf("invalid Content-Length '%s'", value)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, e := io.ReadFull(r, body); e != nil {
+		return nil, e
+	}
+	return body, nil
+}
+
+// Write writes v as the JSON body of one message.
+func Write(w io.Writer, v any) error {
+	body, e := json.Marshal(v)
+	if e != nil {
+		return e
+	}
+
+	if _, e := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); e != nil {
+		return e
+	}
+	_, e = w.Write(body)
+	return e
+}
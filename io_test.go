@@ -0,0 +1,75 @@
+package beremiz_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+func TestReadWords(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		source string
+		want   []any
+	}{
+		{"read-line", "one\ntwo\n", "read-line read-line", []any{"one", "two"}},
+		{"line endings", "one\r\ntwo", "read-line read-line", []any{"one", "two"}},
+		{"empty line", "\nx\n", "read-line read-line", []any{"", "x"}},
+		{"read-line at EOF", "one\n", "read-line read-line", []any{"one", nil}},
+		{"read-line from no input", "", "read-line", []any{nil}},
+		{"read-all", "one\ntwo\n", "read-all", []any{"one\ntwo\n"}},
+		{"read-all after read-line", "one\ntwo\n", "read-line read-all", []any{"one", "two\n"}},
+		{"read-all at EOF", "", "read-all read-line", []any{"", nil}},
+		{"read-number", "42\n-7\n2.5\n", "read-number read-number read-number", []any{int64(42), int64(-7), 2.5}},
+		{"read-number at EOF", "1\n", "read-number read-number", []any{int64(1), nil}},
+		// A loop reading until EOF, the way a filter does.
+		{"filter", "a\nb\nc\n", "0 for read-line dup nil neq do pop 1 + end pop", []any{int64(3)}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			interp := beremiz.New(beremiz.WithStdin(strings.NewReader(c.input)))
+			stack, e := interp.Eval(context.Background(), c.source)
+			if e != nil {
+				t.Fatalf("Eval: %v", e)
+			}
+			if !reflect.DeepEqual(stack, c.want) {
+				t.Errorf("stack = %#v, want %#v", stack, c.want)
+			}
+		})
+	}
+}
+
+func TestReadNumberError(t *testing.T) {
+	for _, input := range []string{"abc\n", "1.2.3\n", "\n"} {
+		_, e := beremiz.New(beremiz.WithStdin(strings.NewReader(input))).Eval(context.Background(), "read-number")
+
+		var d *beremiz.Diagnostic
+		if !errors.As(e, &d) || d.Code != "E305" {
+			t.Errorf("input %q: got %v, want an E305 diagnostic", input, e)
+		}
+	}
+}
+
+// failingReader fails every read.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestReadError(t *testing.T) {
+	for _, word := range []string{"read-line", "read-all", "read-number"} {
+		_, e := beremiz.New(beremiz.WithStdin(failingReader{})).Eval(context.Background(), word)
+
+		var d *beremiz.Diagnostic
+		if !errors.As(e, &d) || d.Code != "E305" || !strings.Contains(d.Message, "broken pipe") {
+			t.Errorf("%s: got %v, want an E305 diagnostic with the read error", word, e)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package beremiz
+
+import (
+	"fmt"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/lexer"
+	"github.com/adaiasmagdiel/beremiz-go/internal/parser"
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// HostFunc implements a word in Go. It finds its operands on top of the
+// stack, already checked against the word's Params, and pushes its results.
+type HostFunc func(stack *Stack) error
+
+// Word is a word implemented by the host program.
+type Word struct {
+	Name string
+	// Params lists the operand types, deepest first. Use Any for operands of
+	// any type.
+	Params []Type
+	// Requires lists the capabilities the word needs. Calls fail with a
+	// *PermissionError unless all of them were granted.
+	Requires []Capability
+	Fn       HostFunc
+}
+
+// Library is a named group of words that can be loaded together.
+type Library struct {
+	Name  string
+	Words []Word
+}
+
+// WithLibraries loads libs into the Interpreter. New panics if one of them
+// is invalid; use Load to get the error instead.
+func WithLibraries(libs ...*Library) Option {
+	return func(i *Interpreter) {
+		if e := i.Load(libs...); e != nil {
+			panic(e)
+		}
+	}
+}
+
+// Load makes every word of libs callable from programs. Names must be valid
+// identifiers that aren't keywords; loading a name twice replaces the
+// previous word.
+func (i *Interpreter) Load(libs ...*Library) error {
+	for _, lib := range libs {
+		for _, w := range lib.Words {
+			if e := validateName(w.Name); e != nil {
+				return fmt.Errorf("library %s: %w", lib.Name, e)
+			}
+			if w.Fn == nil {
+				return fmt.Errorf("library %s: word '%s' has no function", lib.Name, w.Name)
+			}
+		}
+
+		for _, w := range lib.Words {
+			i.words[w.Name] = hostWord(w)
+		}
+	}
+
+	return nil
+}
+
+// Register makes fn callable from programs as name. params are the operand
+// types it expects, deepest first.
+func (i *Interpreter) Register(name string, fn HostFunc, params ...Type) error {
+	return i.Load(&Library{
+		Name:  name,
+		Words: []Word{{Name: name, Params: params, Fn: fn}},
+	})
+}
+
+func validateName(name string) error {
+	ts, e := lexer.New(name, "<word>").Tokenize()
+	if e != nil || len(ts) != 2 || ts[0].Type != tokens.Identifier {
+		return fmt.Errorf("'%s' is not a valid word name", name)
+	}
+	return nil
+}
+
+func hostWord(w Word) parser.Word {
+	fn := w.Fn
+
+	return parser.Word{
+		Name:     w.Name,
+		Params:   w.Params,
+		Requires: w.Requires,
+		Fn: func(token tokens.Token, stack []tokens.Token) ([]tokens.Token, error) {
+			s := &Stack{tokens: stack, loc: token.Loc}
+			e := fn(s)
+			return s.tokens, e
+		},
+	}
+}
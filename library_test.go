@@ -0,0 +1,206 @@
+package beremiz_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+func TestRegister(t *testing.T) {
+	interp := beremiz.New()
+	e := interp.Register("double", func(s *beremiz.Stack) error {
+		n, e := s.PopInt()
+		if e != nil {
+			return e
+		}
+		return s.Push(n * 2)
+	}, beremiz.Int)
+	if e != nil {
+		t.Fatalf("Register: %v", e)
+	}
+
+	stack, e := interp.Eval(context.Background(), `21 double`)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+	if want := []any{int64(42)}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %#v, want %#v", stack, want)
+	}
+
+	// Operands are checked before the function runs.
+	for source, code := range map[string]string{`"a" double`: "E302", `double`: "E301"} {
+		_, e := interp.Eval(context.Background(), source)
+		var d *beremiz.Diagnostic
+		if !errors.As(e, &d) || string(d.Code) != code {
+			t.Errorf("%s: got %v, want %s", source, e, code)
+		}
+	}
+}
+
+func TestHostError(t *testing.T) {
+	failure := errors.New("backend unavailable")
+	interp := beremiz.New()
+	interp.Register("fetch", func(s *beremiz.Stack) error { return failure })
+
+	_, e := interp.Eval(context.Background(), "1\nfetch")
+	var d *beremiz.Diagnostic
+	if !errors.As(e, &d) || d.Code != "E308" || d.Span.StartLine != 2 {
+		t.Fatalf("got %v, want an E308 diagnostic at line 2", e)
+	}
+	if !errors.Is(e, failure) {
+		t.Errorf("got %v, want it to wrap the host error", e)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	noop := func(s *beremiz.Stack) error { return nil }
+
+	lib := &beremiz.Library{Name: "math", Words: []beremiz.Word{
+		{Name: "zero", Fn: func(s *beremiz.Stack) error { return s.Push(0) }},
+		{Name: "add_all", Params: []beremiz.Type{beremiz.Any, beremiz.Any}, Fn: func(s *beremiz.Stack) error {
+			items, e := s.PopList()
+			if e != nil {
+				return e
+			}
+			var sum int64
+			for _, item := range items {
+				sum += item.(int64)
+			}
+			return s.Push(sum)
+		}},
+	}}
+	stack, e := beremiz.New(beremiz.WithLibraries(lib)).Eval(context.Background(), `zero 1 2 3 3 add_all`)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+	if want := []any{int64(0), int64(6)}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %#v, want %#v", stack, want)
+	}
+
+	invalid := []beremiz.Word{
+		{Name: "dup", Fn: noop},
+		{Name: "two words", Fn: noop},
+		{Name: "1st", Fn: noop},
+		{Name: "", Fn: noop},
+		{Name: "no_fn"},
+		{Name: "add-all", Fn: noop},
+	}
+	for _, w := range invalid {
+		if e := beremiz.New().Load(&beremiz.Library{Name: "bad", Words: []beremiz.Word{w}}); e == nil {
+			t.Errorf("Load accepted the word %q", w.Name)
+		}
+	}
+}
+
+// roundTrip pushes v from a host word and pops it back with pop.
+func roundTrip(t *testing.T, v any, pop func(s *beremiz.Stack) (any, error)) any {
+	t.Helper()
+
+	var got any
+	interp := beremiz.New()
+	interp.Register("give", func(s *beremiz.Stack) error { return s.Push(v) })
+	interp.Register("take", func(s *beremiz.Stack) error {
+		var e error
+		got, e = pop(s)
+		return e
+	})
+
+	stack, e := interp.Eval(context.Background(), `give take`)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+	if len(stack) != 0 {
+		t.Errorf("stack = %#v, want it empty", stack)
+	}
+	return got
+}
+
+func TestStackConversions(t *testing.T) {
+	pop := func(s *beremiz.Stack) (any, error) { return s.Pop() }
+	for _, c := range []struct{ in, want any }{
+		{7, int64(7)},
+		{uint8(7), int64(7)},
+		{float32(0.5), 0.5},
+		{"s", "s"},
+		{true, true},
+		{nil, nil},
+	} {
+		if got := roundTrip(t, c.in, pop); got != c.want {
+			t.Errorf("Push(%#v) then Pop = %#v, want %#v", c.in, got, c.want)
+		}
+	}
+
+	list := []any{int64(1), "two", nil}
+	got := roundTrip(t, list, func(s *beremiz.Stack) (any, error) { return s.PopList() })
+	if !reflect.DeepEqual(got, list) {
+		t.Errorf("PopList = %#v, want %#v", got, list)
+	}
+
+	m := map[string]any{"a": int64(1), "b": "two"}
+	got = roundTrip(t, m, func(s *beremiz.Stack) (any, error) { return s.PopMap() })
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("PopMap = %#v, want %#v", got, m)
+	}
+}
+
+func TestStackCountTooLarge(t *testing.T) {
+	interp := beremiz.New()
+	interp.Register("pl", func(s *beremiz.Stack) error { _, e := s.PopList(); return e })
+	interp.Register("pm", func(s *beremiz.Stack) error { _, e := s.PopMap(); return e })
+
+	for _, source := range []string{
+		`1 2 3 pl`,
+		`"a" 1 -1 pl`,
+		`"a" 1 2 pm`,
+		`"a" 1 -1 pm`,
+		`5000000000000000000 pm`,
+		`9223372036854775807 pm`,
+		`9223372036854775807 pl`,
+	} {
+		_, e := interp.Eval(context.Background(), source)
+		var d *beremiz.Diagnostic
+		if !errors.As(e, &d) || d.Code != "E308" {
+			t.Errorf("%s: got %v, want an E308 diagnostic", source, e)
+		}
+	}
+}
+
+func TestStackUnchangedOnPopError(t *testing.T) {
+	interp := beremiz.New()
+	// The words ignore the error, so the stack they leave can be seen.
+	interp.Register("pl", func(s *beremiz.Stack) error {
+		if _, e := s.PopList(); e == nil {
+			return errors.New("PopList succeeded")
+		}
+		return nil
+	})
+	interp.Register("pm", func(s *beremiz.Stack) error {
+		if _, e := s.PopMap(); e == nil {
+			return errors.New("PopMap succeeded")
+		}
+		return nil
+	})
+
+	cases := []struct {
+		source string
+		want   []any
+	}{
+		{`1 2 3 pl`, []any{int64(1), int64(2), int64(3)}},
+		{`"a" 1 -1 pl`, []any{"a", int64(1), int64(-1)}},
+		{`"a" "b" pl`, []any{"a", "b"}},
+		{`"a" 1 2 pm`, []any{"a", int64(1), int64(2)}},
+		{`1 "v" 1 pm`, []any{int64(1), "v", int64(1)}},
+	}
+	for _, c := range cases {
+		stack, e := interp.Eval(context.Background(), c.source)
+		if e != nil {
+			t.Fatalf("%s: %v", c.source, e)
+		}
+		if !reflect.DeepEqual(stack, c.want) {
+			t.Errorf("%s: stack = %#v, want %#v", c.source, stack, c.want)
+		}
+	}
+}
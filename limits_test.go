@@ -0,0 +1,121 @@
+package beremiz_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+func TestLimits(t *testing.T) {
+	cases := []struct {
+		name   string
+		limits beremiz.Limits
+		source string
+		want   error
+	}{
+		{"steps", beremiz.Limits{MaxSteps: 1000}, `for true do end`, beremiz.ErrStepLimit},
+		{"stack depth", beremiz.Limits{MaxStackDepth: 10}, `for true do 1 end`, beremiz.ErrStackLimit},
+		{"call depth", beremiz.Limits{MaxCallDepth: 2}, `define a 1 end define b a end define c b end c`, beremiz.ErrCallDepth},
+		{"string length", beremiz.Limits{MaxStringLen: 8}, `"ab" for true do dup . end`, beremiz.ErrStringLimit},
+		{"timeout", beremiz.Limits{Timeout: 20 * time.Millisecond}, `for true do end`, context.DeadlineExceeded},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, e := beremiz.New(beremiz.WithLimits(c.limits)).Eval(context.Background(), c.source)
+			if !errors.Is(e, c.want) {
+				t.Fatalf("got %v, want %v", e, c.want)
+			}
+
+			var d *beremiz.Diagnostic
+			if !errors.As(e, &d) || d.Code != "E307" {
+				t.Errorf("got %v, want an E307 diagnostic", e)
+			}
+		})
+	}
+}
+
+func TestStringLimitUnderTop(t *testing.T) {
+	// The limit lets the path of dir through, but not the names pushed
+	// under the top of the stack.
+	dir := t.TempDir()
+	limits := beremiz.Limits{MaxStringLen: len(dir)}
+	long := strings.Repeat("x", len(dir)+1)
+	if e := os.WriteFile(filepath.Join(dir, long), nil, 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	words := &beremiz.Library{Name: "lists", Words: []beremiz.Word{{
+		Name: "names",
+		Fn:   func(s *beremiz.Stack) error { return s.PushList([]any{long, "b"}) },
+	}, {
+		// swap_long pops the top and pushes a long string under it.
+		Name: "swap_long",
+		Fn: func(s *beremiz.Stack) error {
+			top, e := s.Pop()
+			if e != nil {
+				return e
+			}
+			s.Pop()
+			s.Push(long)
+			return s.Push(top)
+		},
+	}}}
+
+	cases := []struct {
+		name   string
+		opts   []beremiz.Option
+		source string
+	}{
+		{"args", []beremiz.Option{beremiz.WithArgs(long, "b"), beremiz.WithCapabilities(beremiz.Process)}, `args`},
+		{"list-dir", []beremiz.Option{beremiz.WithCapabilities(beremiz.FSRead)}, strconv.Quote(dir) + ` list-dir`},
+		{"host word list", nil, `names`},
+		{"host word under the top", nil, `"a" 1 swap_long`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := append(c.opts, beremiz.WithLimits(limits), beremiz.WithLibraries(words))
+			_, e := beremiz.New(opts...).Eval(context.Background(), c.source)
+			if !errors.Is(e, beremiz.ErrStringLimit) {
+				t.Errorf("got %v, want %v", e, beremiz.ErrStringLimit)
+			}
+		})
+	}
+}
+
+func TestLimitsNotReached(t *testing.T) {
+	limits := beremiz.Limits{
+		MaxSteps:      100,
+		MaxStackDepth: 4,
+		MaxCallDepth:  2,
+		MaxStringLen:  4,
+		Timeout:       time.Second,
+	}
+	source := `define b "ab" end define a b b . end 1 2 a`
+
+	stack, e := beremiz.New(beremiz.WithLimits(limits)).Eval(context.Background(), source)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+	if len(stack) != 3 || stack[2] != "abab" {
+		t.Errorf("stack = %#v, want [1 2 \"abab\"]", stack)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, e := beremiz.New().Eval(ctx, `for true do end`)
+	if !errors.Is(e, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", e)
+	}
+}
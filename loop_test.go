@@ -0,0 +1,58 @@
+package beremiz_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+func TestLoops(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   []any
+	}{
+		{"loop at the start", `for depth 3 < do 1 end`, []any{int64(1), int64(1), int64(1)}},
+		{"if inside a loop", `0 for dup 3 < do if dup 1 eq do 10 swap end 1 + end`, []any{int64(10), int64(3)}},
+		{"word called in a loop", `define inc 1 + end 0 for dup 3 < do inc end`, []any{int64(3)}},
+		{"nested loops", `0 for dup 2 < do 0 for dup 2 < do 1 + end pop 1 + end`, []any{int64(2)}},
+		{"loop inside a word", `define count 0 for dup 3 < do 1 + end end count`, []any{int64(3)}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, e := beremiz.New().Eval(context.Background(), c.source)
+			if e != nil {
+				t.Fatalf("Eval: %v", e)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("stack = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// writes records every call to Write separately.
+type writes []string
+
+func (w *writes) Write(b []byte) (int, error) {
+	*w = append(*w, string(b))
+	return len(b), nil
+}
+
+func TestLoopOutputFlush(t *testing.T) {
+	var out writes
+	source := `for depth 2 < do "a" writeln 1 end "b" writeln "c" writeln`
+	if _, e := beremiz.New(beremiz.WithStdout(&out)).Eval(context.Background(), source); e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+
+	// Lines are flushed one by one inside the loop, and buffered again once
+	// it is left.
+	want := writes{"a\n", "a\n", "b\nc\n"}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("writes = %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,50 @@
+package beremiz_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+func TestUnknownNames(t *testing.T) {
+	interp := beremiz.New()
+	interp.Register("greet", func(s *beremiz.Stack) error { return nil })
+
+	// Names are checked before anything runs, even in branches that never
+	// would.
+	source := "define square dup * end\n\"never\" writeln\nif false do 1 2 swpa squar gret end"
+	_, e := interp.Eval(context.Background(), source)
+
+	var list beremiz.Diagnostics
+	if !errors.As(e, &list) || len(list) != 3 {
+		t.Fatalf("got %v, want 3 diagnostics", e)
+	}
+	var suggestions []string
+	for _, d := range list {
+		if d.Code != "E204" || d.Code.Kind() != "SyntaxError" {
+			t.Errorf("got %s (%s), want E204 (SyntaxError)", d.Code, d.Code.Kind())
+		}
+		suggestions = append(suggestions, d.Suggestions...)
+	}
+
+	want := []string{"did you mean 'swap'?", "did you mean 'square'?", "did you mean 'greet'?"}
+	if !reflect.DeepEqual(suggestions, want) {
+		t.Errorf("suggestions = %q, want %q", suggestions, want)
+	}
+}
+
+func TestHostWordsWithLexerErrors(t *testing.T) {
+	interp := beremiz.New()
+	interp.Register("greet", func(s *beremiz.Stack) error { return nil })
+
+	// Only the lexer error is reported: host words are known names.
+	_, e := interp.Eval(context.Background(), "greet\n\"unterminated")
+
+	var list beremiz.Diagnostics
+	if !errors.As(e, &list) || len(list) != 1 || list[0].Code != "E103" {
+		t.Errorf("got %v, want only E103", e)
+	}
+}
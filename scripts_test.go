@@ -0,0 +1,65 @@
+package beremiz_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+func TestSetenv(t *testing.T) {
+	t.Setenv("BEREMIZ_TEST_VAR", "")
+
+	interp := beremiz.New(beremiz.WithCapabilities(beremiz.Env))
+	stack, e := interp.Eval(context.Background(), `1 "value" "BEREMIZ_TEST_VAR" setenv`)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+
+	// setenv takes both of its operands and leaves the rest alone.
+	if want := []any{int64(1)}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %v, want %v", stack, want)
+	}
+	if got := os.Getenv("BEREMIZ_TEST_VAR"); got != "value" {
+		t.Errorf("BEREMIZ_TEST_VAR = %q, want %q", got, "value")
+	}
+
+	stack, e = interp.Eval(context.Background(), `"BEREMIZ_TEST_VAR" getenv`)
+	if e != nil {
+		t.Fatalf("Eval: %v", e)
+	}
+	if want := []any{"value"}; !reflect.DeepEqual(stack, want) {
+		t.Errorf("stack = %v, want %v", stack, want)
+	}
+}
+
+func TestExit(t *testing.T) {
+	interp := beremiz.New(beremiz.WithCapabilities(beremiz.Process))
+
+	for source, code := range map[string]int{"0 exit": 0, "3 exit": 3, "255 exit": 255} {
+		_, e := interp.Eval(context.Background(), source)
+
+		var exit *beremiz.ExitError
+		if !errors.As(e, &exit) || exit.Code != code {
+			t.Errorf("%s: got %v, want exit status %d", source, e, code)
+		}
+	}
+
+	for _, source := range []string{"256 exit", "-1 exit", "9999999999 exit"} {
+		_, e := interp.Eval(context.Background(), source)
+
+		var d *beremiz.Diagnostic
+		if !errors.As(e, &d) || d.Code != "E309" {
+			t.Errorf("%s: got %v, want an E309 diagnostic", source, e)
+		}
+	}
+
+	_, e := interp.Eval(context.Background(), `"1" exit`)
+	var d *beremiz.Diagnostic
+	if !errors.As(e, &d) || d.Code != "E302" {
+		t.Errorf(`"1" exit: got %v, want an E302 diagnostic`, e)
+	}
+}
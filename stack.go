@@ -0,0 +1,264 @@
+package beremiz
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/adaiasmagdiel/beremiz-go/internal/tokens"
+)
+
+// Type is the type of a Beremiz value, as reported by the type word.
+type Type = tokens.TokenType
+
+const (
+	Int    Type = tokens.Int
+	Float  Type = tokens.Float
+	String Type = tokens.String
+	Bool   Type = tokens.Bool
+	Nil    Type = tokens.Nil
+	// Any accepts a value of any type in a word's parameter list.
+	Any Type = ""
+)
+
+// ErrStackUnderflow is returned when popping from an empty stack.
+var ErrStackUnderflow = errors.New("stack is empty")
+
+// Stack is the data stack as seen by host words.
+//
+// Values are int64, float64, string, bool or nil. Beremiz has no list or
+// map values, so PushList and PushMap follow the convention of args and
+// list-dir: the items are pushed one by one, followed by their count.
+type Stack struct {
+	tokens []tokens.Token
+	loc    tokens.Loc
+}
+
+// Len returns the number of values on the stack.
+func (s *Stack) Len() int {
+	return len(s.tokens)
+}
+
+// Push pushes v. Go integers and floats are converted to int64 and float64,
+// []any is pushed with PushList and map[string]any with PushMap.
+func (s *Stack) Push(v any) error {
+	switch v := v.(type) {
+	case []any:
+		return s.PushList(v)
+	case map[string]any:
+		return s.PushMap(v)
+	}
+
+	t, e := toToken(v, s.loc)
+	if e != nil {
+		return e
+	}
+	s.tokens = append(s.tokens, t)
+	return nil
+}
+
+// Pop removes and returns the top value.
+func (s *Stack) Pop() (any, error) {
+	if len(s.tokens) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	top := s.tokens[len(s.tokens)-1]
+	s.tokens = s.tokens[:len(s.tokens)-1]
+	return value(top), nil
+}
+
+// Peek returns the top value without removing it.
+func (s *Stack) Peek() (any, error) {
+	if len(s.tokens) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	return value(s.tokens[len(s.tokens)-1]), nil
+}
+
+func (s *Stack) popType(want Type) (any, error) {
+	if len(s.tokens) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	if top := s.tokens[len(s.tokens)-1]; top.Type != want {
+		return nil, fmt.Errorf("expected %s on top of the stack, but got %s", want, top.Type)
+	}
+	return s.Pop()
+}
+
+// PopInt pops an int.
+func (s *Stack) PopInt() (int64, error) {
+	v, e := s.popType(Int)
+	if e != nil {
+		return 0, e
+	}
+	return v.(int64), nil
+}
+
+// PopFloat pops a float. Ints are converted.
+func (s *Stack) PopFloat() (float64, error) {
+	if len(s.tokens) > 0 && s.tokens[len(s.tokens)-1].Type == Int {
+		n, e := s.PopInt()
+		return float64(n), e
+	}
+	v, e := s.popType(Float)
+	if e != nil {
+		return 0, e
+	}
+	return v.(float64), nil
+}
+
+// PopString pops a string.
+func (s *Stack) PopString() (string, error) {
+	v, e := s.popType(String)
+	if e != nil {
+		return "", e
+	}
+	return v.(string), nil
+}
+
+// PopBool pops a bool.
+func (s *Stack) PopBool() (bool, error) {
+	v, e := s.popType(Bool)
+	if e != nil {
+		return false, e
+	}
+	return v.(bool), nil
+}
+
+// PushList pushes every item, then the number of items.
+func (s *Stack) PushList(items []any) error {
+	for _, item := range items {
+		if e := s.Push(item); e != nil {
+			return e
+		}
+	}
+	return s.Push(len(items))
+}
+
+// count returns the int on top of the stack, the count before a list or a
+// map, without popping it.
+func (s *Stack) count() (int64, error) {
+	if len(s.tokens) == 0 {
+		return 0, ErrStackUnderflow
+	}
+	top := s.tokens[len(s.tokens)-1]
+	if top.Type != Int {
+		return 0, fmt.Errorf("expected %s on top of the stack, but got %s", Int, top.Type)
+	}
+	return top.Literal.(int64), nil
+}
+
+// PopList pops a count and then that many items, returned in push order.
+// On error the stack is left as it was.
+func (s *Stack) PopList() ([]any, error) {
+	n, e := s.count()
+	if e != nil {
+		return nil, e
+	}
+	below := len(s.tokens) - 1
+	if n < 0 || n > int64(below) {
+		return nil, fmt.Errorf("list of %d items doesn't fit a stack of %d values", n, below)
+	}
+
+	items := values(s.tokens[below-int(n) : below])
+	s.tokens = s.tokens[:below-int(n)]
+	return items, nil
+}
+
+// PushMap pushes each key and value, sorted by key, then the number of
+// pairs.
+func (s *Stack) PushMap(m map[string]any) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if e := s.Push(k); e != nil {
+			return e
+		}
+		if e := s.Push(m[k]); e != nil {
+			return e
+		}
+	}
+	return s.Push(len(m))
+}
+
+// PopMap pops a pair count and then that many key/value pairs. On error the
+// stack is left as it was.
+func (s *Stack) PopMap() (map[string]any, error) {
+	n, e := s.count()
+	if e != nil {
+		return nil, e
+	}
+	below := len(s.tokens) - 1
+	if n < 0 || n > int64(below/2) {
+		return nil, fmt.Errorf("map of %d pairs doesn't fit a stack of %d values", n, below)
+	}
+
+	size := int(2 * n)
+	pairs := s.tokens[below-size : below]
+	m := make(map[string]any, n)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].Literal.(string)
+		if pairs[i].Type != String || !ok {
+			return nil, fmt.Errorf("map keys must be strings, but got %s", pairs[i].Type)
+		}
+		m[key] = value(pairs[i+1])
+	}
+	s.tokens = s.tokens[:below-size]
+	return m, nil
+}
+
+func toToken(v any, loc tokens.Loc) (tokens.Token, error) {
+	t := tokens.Token{Loc: loc}
+
+	switch v := v.(type) {
+	case nil:
+		t.Type, t.Literal = tokens.Nil, "nil"
+	case bool:
+		t.Type, t.Literal = tokens.Bool, v
+	case string:
+		t.Type, t.Literal = tokens.String, v
+	case int:
+		t.Type, t.Literal = tokens.Int, int64(v)
+	case int8:
+		t.Type, t.Literal = tokens.Int, int64(v)
+	case int16:
+		t.Type, t.Literal = tokens.Int, int64(v)
+	case int32:
+		t.Type, t.Literal = tokens.Int, int64(v)
+	case int64:
+		t.Type, t.Literal = tokens.Int, v
+	case uint8:
+		t.Type, t.Literal = tokens.Int, int64(v)
+	case uint16:
+		t.Type, t.Literal = tokens.Int, int64(v)
+	case uint32:
+		t.Type, t.Literal = tokens.Int, int64(v)
+	case float32:
+		t.Type, t.Literal = tokens.Float, float64(v)
+	case float64:
+		t.Type, t.Literal = tokens.Float, v
+	default:
+		return t, fmt.Errorf("unsupported value type %T", v)
+	}
+
+	return t, nil
+}
+
+func values(stack []tokens.Token) []any {
+	vs := make([]any, len(stack))
+	for idx, t := range stack {
+		vs[idx] = value(t)
+	}
+	return vs
+}
+
+func value(t tokens.Token) any {
+	if t.Type == tokens.Nil {
+		return nil
+	}
+	return t.Literal
+}
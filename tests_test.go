@@ -0,0 +1,98 @@
+package beremiz_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	beremiz "github.com/adaiasmagdiel/beremiz-go"
+)
+
+// runTests runs every test of the file at path with interp and returns the
+// error of each, by name.
+func runTests(t *testing.T, interp *beremiz.Interpreter, path string) map[string]error {
+	t.Helper()
+	source, e := os.ReadFile(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	tests, e := interp.Tests(path, string(source))
+	if e != nil {
+		t.Fatalf("Tests: %v", e)
+	}
+	results := map[string]error{}
+	for _, test := range tests {
+		results[test.Name] = interp.RunTest(context.Background(), path, string(source), test)
+	}
+	return results
+}
+
+func TestTests(t *testing.T) {
+	cases := []struct {
+		file string
+		// failed lists the tests expected to fail, with an E401 diagnostic.
+		failed []string
+		passed []string
+	}{
+		{"pass_test.brz", nil, []string{"assert", "assert-eq", "assert-stack", "assert-throws", "word with a block"}},
+		{"fail_test.brz", []string{"assert", "assert-eq", "assert-stack", "assert-throws"}, []string{"passing"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			results := runTests(t, beremiz.New(), filepath.Join("testdata", c.file))
+			if len(results) != len(c.failed)+len(c.passed) {
+				t.Errorf("ran %d tests, want %d", len(results), len(c.failed)+len(c.passed))
+			}
+
+			for _, name := range c.passed {
+				if e, ok := results[name]; !ok || e != nil {
+					t.Errorf("test %q: got %v (found: %v), want it to pass", name, e, ok)
+				}
+			}
+			for _, name := range c.failed {
+				var d *beremiz.Diagnostic
+				if e := results[name]; !errors.As(e, &d) || d.Code != "E401" {
+					t.Errorf("test %q: got %v, want an E401 diagnostic", name, e)
+				}
+			}
+		})
+	}
+}
+
+func TestTestsWithHostWords(t *testing.T) {
+	interp := beremiz.New()
+	interp.Register("double", func(s *beremiz.Stack) error {
+		n, e := s.PopInt()
+		if e != nil {
+			return e
+		}
+		return s.Push(n * 2)
+	}, beremiz.Int)
+
+	results := runTests(t, interp, filepath.Join("testdata", "host_test.brz"))
+	if want := map[string]error{"host word": nil}; !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+}
+
+func TestTestsDontRunTheProgram(t *testing.T) {
+	source := `"outside" writeln test "t" depth 0 assert-eq end`
+
+	var out writes
+	interp := beremiz.New(beremiz.WithStdout(&out))
+	tests, e := interp.Tests("t.brz", source)
+	if e != nil || len(tests) != 1 {
+		t.Fatalf("Tests = %v, %v; want one test", tests, e)
+	}
+	if e := interp.RunTest(context.Background(), "t.brz", source, tests[0]); e != nil {
+		t.Errorf("RunTest: %v", e)
+	}
+	if len(out) != 0 {
+		t.Errorf("output = %q, want none", out)
+	}
+}